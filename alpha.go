@@ -0,0 +1,96 @@
+package palettor
+
+import "image/color"
+
+// An AlphaPolicy selects how transparent and translucent pixels are treated
+// when extracting a palette.
+type AlphaPolicy int
+
+// Supported alpha policies
+const (
+	// AlphaIgnore clusters every pixel by its alpha-premultiplied color, so
+	// that fully transparent pixels count as black.
+	AlphaIgnore AlphaPolicy = iota
+
+	// AlphaSkip skips pixels whose alpha is at or below
+	// Options.AlphaThreshold, and clusters the remaining pixels by their
+	// color as if they were opaque.
+	AlphaSkip
+
+	// AlphaWeight weights each pixel by its alpha, so that translucent pixels
+	// count for less than opaque ones and fully transparent pixels are
+	// skipped. Pixels are clustered by their color as if they were opaque.
+	AlphaWeight
+
+	// AlphaComposite composites each pixel over Options.Background before
+	// clustering.
+	AlphaComposite
+)
+
+// applyAlphaPolicy returns the colors to cluster under the given options,
+// along with the weight of each color, or nil weights if every color has
+// weight 1.
+func applyAlphaPolicy(colors []color.RGBA64, opts Options) ([]color.RGBA64, []float32) {
+	switch opts.Alpha {
+	case AlphaSkip:
+		threshold := uint16(opts.AlphaThreshold * 0xffff)
+		result := make([]color.RGBA64, 0, len(colors))
+		for _, c := range colors {
+			if c.A > threshold {
+				result = append(result, opaque(c))
+			}
+		}
+		return result, nil
+	case AlphaWeight:
+		result := make([]color.RGBA64, 0, len(colors))
+		weights := make([]float32, 0, len(colors))
+		for _, c := range colors {
+			if c.A > 0 {
+				result = append(result, opaque(c))
+				weights = append(weights, float32(c.A)/0xffff)
+			}
+		}
+		return result, weights
+	case AlphaComposite:
+		background := color.RGBA64{0xffff, 0xffff, 0xffff, 0xffff}
+		if opts.Background != nil {
+			background = toRGBA64(opts.Background)
+		}
+		result := make([]color.RGBA64, len(colors))
+		for i, c := range colors {
+			result[i] = composite(c, background)
+		}
+		return result, nil
+	default:
+		return colors, nil
+	}
+}
+
+// opaque returns the fully opaque version of an alpha-premultiplied color.
+func opaque(c color.RGBA64) color.RGBA64 {
+	if c.A == 0xffff {
+		return c
+	}
+	unpremultiply := func(x uint16) uint16 {
+		return uint16(uint32(x) * 0xffff / uint32(c.A))
+	}
+	return color.RGBA64{
+		R: unpremultiply(c.R),
+		G: unpremultiply(c.G),
+		B: unpremultiply(c.B),
+		A: 0xffff,
+	}
+}
+
+// composite returns the result of drawing a color over a background color.
+func composite(c, background color.RGBA64) color.RGBA64 {
+	over := func(x, y uint16) uint16 {
+		return uint16(uint32(x) + uint32(y)*(0xffff-uint32(c.A))/0xffff)
+	}
+	return color.RGBA64{
+		R: over(c.R, background.R),
+		G: over(c.G, background.G),
+		B: over(c.B, background.B),
+		A: over(c.A, background.A),
+	}
+}
@@ -0,0 +1,100 @@
+package palettor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var (
+	transparent     = color.NRGBA{0, 0, 0, 0}
+	translucentBlue = color.NRGBA{0, 0, 255, 128}
+)
+
+func TestApplyAlphaPolicy(t *testing.T) {
+	colors := rgba64s(transparent, opaqueRed, translucentBlue)
+
+	result, weights := applyAlphaPolicy(colors, Options{Alpha: AlphaIgnore})
+	if len(result) != 3 || weights != nil {
+		t.Errorf("AlphaIgnore: expected all colors to be included, got %v", result)
+	}
+
+	result, _ = applyAlphaPolicy(colors, Options{Alpha: AlphaSkip})
+	expected := []color.RGBA64{
+		color.RGBA64{0xffff, 0, 0, 0xffff},
+		color.RGBA64{0, 0, 0xffff, 0xffff},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("AlphaSkip: expected %v, got %v", expected, result)
+	}
+
+	result, _ = applyAlphaPolicy(colors, Options{Alpha: AlphaSkip, AlphaThreshold: 0.75})
+	expected = []color.RGBA64{color.RGBA64{0xffff, 0, 0, 0xffff}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("AlphaSkip w/ threshold: expected %v, got %v", expected, result)
+	}
+
+	result, weights = applyAlphaPolicy(colors, Options{Alpha: AlphaWeight})
+	expected = []color.RGBA64{
+		color.RGBA64{0xffff, 0, 0, 0xffff},
+		color.RGBA64{0, 0, 0xffff, 0xffff},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("AlphaWeight: expected %v, got %v", expected, result)
+	}
+	if len(weights) != 2 || weights[0] != 1 || math.Abs(float64(weights[1])-128.0/255) > 1e-6 {
+		t.Errorf("AlphaWeight: expected weights to match alpha, got %v", weights)
+	}
+
+	result, _ = applyAlphaPolicy(colors, Options{Alpha: AlphaComposite})
+	expected = []color.RGBA64{
+		color.RGBA64{0xffff, 0xffff, 0xffff, 0xffff},
+		color.RGBA64{0xffff, 0, 0, 0xffff},
+		color.RGBA64{0x7f7f, 0x7f7f, 0xffff, 0xffff},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("AlphaComposite: expected %v, got %v", expected, result)
+	}
+
+	result, _ = applyAlphaPolicy(colors, Options{Alpha: AlphaComposite, Background: color.Black})
+	expected = []color.RGBA64{
+		color.RGBA64{0, 0, 0, 0xffff},
+		color.RGBA64{0xffff, 0, 0, 0xffff},
+		color.RGBA64{0, 0, 0x8080, 0xffff},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("AlphaComposite w/ background: expected %v, got %v", expected, result)
+	}
+}
+
+func TestExtractAlpha(t *testing.T) {
+	// A red logo on a mostly transparent background
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, opaqueRed)
+
+	palette, err := ExtractWithOptions(img, Options{K: 2, Init: InitKMeansPlusPlus})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Weight(transparent) != 0.75 {
+		t.Errorf("AlphaIgnore: expected transparent pixels to dominate, got %v", palette.Entries())
+	}
+
+	palette, err = ExtractWithOptions(img, Options{K: 1, Alpha: AlphaSkip})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Weight(color.RGBA64{0xffff, 0, 0, 0xffff}) != 1 {
+		t.Errorf("AlphaSkip: expected only red, got %v", palette.Entries())
+	}
+
+	if _, err := ExtractWithOptions(img, Options{K: 2, Alpha: AlphaSkip}); err == nil {
+		t.Errorf("AlphaSkip: too few opaque pixels for k, expected an error")
+	}
+
+	if _, err := ExtractWithOptions(img, Options{K: 1, Alpha: AlphaSkip, AlphaThreshold: 2}); err == nil {
+		t.Errorf("alpha threshold out of range, expected an error")
+	}
+}
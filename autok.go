@@ -0,0 +1,264 @@
+package palettor
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Default range of k values tried by ExtractAutoK
+const (
+	DefaultMinK = 2
+	DefaultMaxK = 10
+)
+
+// An AutoKMethod selects how ExtractAutoK picks the best k.
+type AutoKMethod int
+
+// Supported auto k methods
+const (
+	// AutoKSilhouette picks the k with the highest mean silhouette score,
+	// i.e. the k whose clusters are the most cohesive and best separated.
+	AutoKSilhouette AutoKMethod = iota
+
+	// AutoKElbow picks the k at the "elbow" of the curve of inertia against
+	// k, beyond which adding clusters stops paying off.
+	AutoKElbow
+)
+
+// AutoKOptions configures the range of k values tried by ExtractAutoK, and
+// how the best k is picked.
+type AutoKOptions struct {
+	// MinK and MaxK are the smallest and largest k to try. They default to
+	// DefaultMinK and DefaultMaxK.
+	MinK int
+	MaxK int
+
+	// Method picks the best k, and defaults to AutoKSilhouette.
+	Method AutoKMethod
+}
+
+// withDefaults returns a copy of the options with defaults filled in, or an
+// error if the options are invalid.
+func (o AutoKOptions) withDefaults() (AutoKOptions, error) {
+	if o.MinK == 0 {
+		o.MinK = DefaultMinK
+	}
+	if o.MaxK == 0 {
+		o.MaxK = DefaultMaxK
+	}
+	if o.MinK < 1 {
+		return o, fmt.Errorf("min k must be positive (got %d)", o.MinK)
+	}
+	if o.MaxK < o.MinK {
+		return o, fmt.Errorf("max k must be at least min k (got %d < %d)", o.MaxK, o.MinK)
+	}
+	if o.Method != AutoKSilhouette && o.Method != AutoKElbow {
+		return o, fmt.Errorf("unknown auto k method %d", o.Method)
+	}
+	return o, nil
+}
+
+// AutoKScore scores the clusters found for one value of k.
+type AutoKScore struct {
+	K int `json:"k"`
+
+	// Inertia is the weighted sum of squared distances from each pixel to
+	// its nearest centroid, measured by the metric used for clustering.
+	// Lower is better, but it tends to shrink as k grows, so it is only
+	// meaningful compared across k values, as by AutoKElbow.
+	Inertia float64 `json:"inertia"`
+
+	// Silhouette is the weighted mean simplified silhouette[1] of the
+	// pixels, in the range [-1, 1], based on each pixel's distance to its
+	// nearest and second-nearest centroids. Higher is better. It is 0 when
+	// there is only one cluster.
+	//
+	// [1]: https://en.wikipedia.org/wiki/Silhouette_(clustering)#Simplified_Silhouette_and_Medoid_Silhouette
+	Silhouette float64 `json:"silhouette"`
+}
+
+// AutoKResult is the result of ExtractAutoK.
+type AutoKResult struct {
+	// K is the chosen k, and Palette is the palette extracted with it.
+	K       int
+	Palette *Palette
+
+	// Scores holds the scores of every k tried, in increasing order of k.
+	Scores []AutoKScore
+}
+
+// ExtractAutoK extracts a palette from the given image for every k in the
+// range given by auto, as configured by opts, and picks the best k using
+// auto.Method. opts.K is ignored.
+//
+// Every k is clustered from the same sampled pixels, so the cost of
+// ExtractAutoK grows with the number of k values tried. k values larger than
+// the number of distinct colors to cluster are skipped. So that every k
+// really yields k clusters, opts.EmptyClusters defaults to EmptySplitLargest
+// rather than EmptyDrop; any k whose palette still has fewer than k colors is
+// skipped, so the chosen K always equals the palette's Count.
+func ExtractAutoK(ctx context.Context, img image.Image, opts Options, auto AutoKOptions) (*AutoKResult, error) {
+	auto, err := auto.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	opts.K = auto.MinK
+
+	var result *AutoKResult
+	_, err = extract(ctx, img, opts, func(ctx context.Context, colors []color.RGBA64, opts Options) (*Palette, error) {
+		result, err = autoK(ctx, colors, opts, auto)
+		if err != nil {
+			return nil, err
+		}
+		return result.Palette, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// autoK clusters the given colors for every k in the range given by auto, and
+// picks the best k.
+func autoK(ctx context.Context, colors []color.RGBA64, opts Options, auto AutoKOptions) (*AutoKResult, error) {
+	colors, weights, counts, _, err := prepareColors(colors, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.EmptyClusters == EmptyDrop {
+		opts.EmptyClusters = EmptySplitLargest
+	}
+	metric := opts.metric()
+	px := newPixels(colors, weights, counts, metric.Space())
+
+	maxK := distinctColors(colors, auto.MaxK)
+	if maxK < auto.MinK {
+		return nil, fmt.Errorf("too few colors for min k (%d < %d)", maxK, auto.MinK)
+	}
+	palettes := make([]*Palette, 0, maxK-auto.MinK+1)
+	scores := make([]AutoKScore, 0, maxK-auto.MinK+1)
+	for k := auto.MinK; k <= maxK; k++ {
+		palette, centroids, err := cluster(ctx, px, k, opts)
+		if err != nil {
+			return nil, err
+		}
+		if palette.Count() < k {
+			continue
+		}
+		palettes = append(palettes, palette)
+		scores = append(scores, AutoKScore{
+			K:          k,
+			Inertia:    palette.Inertia(),
+			Silhouette: silhouette(centroids, px, metric, opts.workers()),
+		})
+	}
+
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("no k in the range [%d, %d] gave a palette of k colors", auto.MinK, maxK)
+	}
+
+	best := 0
+	if auto.Method == AutoKElbow {
+		best = elbow(scores)
+	} else {
+		for i, score := range scores {
+			if score.Silhouette > scores[best].Silhouette {
+				best = i
+			}
+		}
+	}
+	return &AutoKResult{
+		K:       scores[best].K,
+		Palette: palettes[best],
+		Scores:  scores,
+	}, nil
+}
+
+// distinctColors returns the number of distinct colors in the given colors,
+// counting no further than limit.
+func distinctColors(colors []color.RGBA64, limit int) int {
+	seen := make(map[color.RGBA64]bool, limit)
+	for _, c := range colors {
+		if len(seen) >= limit {
+			break
+		}
+		seen[c] = true
+	}
+	return len(seen)
+}
+
+// silhouette computes the weighted mean simplified silhouette of the clusters
+// given by the nearest of the given centroids to each pixel.
+func silhouette(centroids []centroid, px *pixels, metric Metric, workers int) float64 {
+	if len(centroids) < 2 {
+		return 0
+	}
+	partials := make([]float64, chunkCount(px.count()))
+	forEachChunk(px.count(), workers, func(chunk, start, end int) {
+		var sum float64
+		for x := start; x < end; x++ {
+			p := px.point(x)
+
+			// a is the distance to the nearest centroid, and b the distance
+			// to the second nearest.
+			a, b := math.Inf(1), math.Inf(1)
+			for _, c := range centroids {
+				d := metric.Distance(c.point, p)
+				if d < a {
+					a, b = d, a
+				} else if d < b {
+					b = d
+				}
+			}
+			if b > 0 {
+				sum += px.weight(x) * (b - a) / b
+			}
+		}
+		partials[chunk] = sum
+	})
+
+	var sum float64
+	for _, partial := range partials {
+		sum += partial
+	}
+	return sum / px.totalWeight()
+}
+
+// elbow returns the index of the score at the elbow of the curve of inertia
+// against k, found as the point furthest from the straight line between the
+// first and last points once both axes are normalized, as in the Kneedle
+// algorithm[1]. Because the furthest point is never the last, the first k
+// with zero inertia, whose clusters fit the colors exactly, is picked instead
+// if there is one; beyond it, every extra cluster is wasted.
+//
+// [1]: Satopää et al., Finding a "Kneedle" in a Haystack, 2011
+func elbow(scores []AutoKScore) int {
+	for i, score := range scores {
+		if score.Inertia == 0 {
+			return i
+		}
+	}
+
+	first, last := scores[0], scores[len(scores)-1]
+	kRange := float64(last.K - first.K)
+	inertiaRange := first.Inertia - last.Inertia
+	if kRange == 0 || inertiaRange <= 0 {
+		return 0
+	}
+
+	// With both axes normalized to [0, 1], the line runs from (0, 1) to
+	// (1, 0), so a point's distance from it is proportional to how far
+	// x + y falls below 1.
+	best, bestDist := 0, 0.0
+	for i, score := range scores {
+		x := float64(score.K-first.K) / kRange
+		y := (score.Inertia - last.Inertia) / inertiaRange
+		if dist := 1 - x - y; dist > bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
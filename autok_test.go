@@ -0,0 +1,173 @@
+package palettor
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// blocksImage returns an image made of vertical stripes of the given colors,
+// with the given widths.
+func blocksImage(colors []color.Color, widths []int) image.Image {
+	var width int
+	for _, w := range widths {
+		width += w
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, 10))
+	x := 0
+	for i, c := range colors {
+		draw.Draw(img, image.Rect(x, 0, x+widths[i], 10), &image.Uniform{c}, image.Point{}, draw.Src)
+		x += widths[i]
+	}
+	return img
+}
+
+func TestExtractAutoK(t *testing.T) {
+	colors := []color.Color{
+		color.RGBA{220, 20, 20, 255},
+		color.RGBA{20, 200, 20, 255},
+		color.RGBA{20, 20, 220, 255},
+		color.RGBA{240, 240, 240, 255},
+	}
+	img := blocksImage(colors, []int{40, 30, 20, 10})
+	opts := Options{Seed: 1, Init: InitKMeansPlusPlus}
+
+	for _, method := range []AutoKMethod{AutoKSilhouette, AutoKElbow} {
+		result, err := ExtractAutoK(context.Background(), img, opts, AutoKOptions{MinK: 1, MaxK: 8, Method: method})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result.K != 4 {
+			t.Errorf("method %d: expected k = 4, got %d (scores: %v)", method, result.K, result.Scores)
+		}
+		if result.Palette.Count() != 4 {
+			t.Errorf("method %d: expected 4 colors, got %d", method, result.Palette.Count())
+		}
+		for _, c := range colors {
+			if result.Palette.Weight(c) == 0 {
+				t.Errorf("method %d: expected %v in palette, got %v", method, c, result.Palette.Entries())
+			}
+		}
+
+		// k values beyond the 4 distinct colors are skipped
+		if len(result.Scores) != 4 {
+			t.Fatalf("method %d: expected 4 scores, got %d", method, len(result.Scores))
+		}
+		for i, score := range result.Scores {
+			if score.K != i+1 {
+				t.Errorf("method %d: expected score %d to be for k = %d, got %d", method, i, i+1, score.K)
+			}
+		}
+		if s := result.Scores[0].Silhouette; s != 0 {
+			t.Errorf("method %d: expected silhouette of 0 for k = 1, got %v", method, s)
+		}
+		if i := result.Scores[3].Inertia; i != 0 {
+			t.Errorf("method %d: expected inertia of 0 for k = 4, got %v", method, i)
+		}
+	}
+}
+
+func TestExtractAutoKFewColors(t *testing.T) {
+	colors := []color.Color{
+		color.RGBA{200, 30, 30, 255},
+		color.RGBA{30, 200, 30, 255},
+		color.RGBA{30, 30, 200, 255},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, colors[(x/7+y/7)%3])
+		}
+	}
+
+	// With the default options, which would otherwise drop clusters whose
+	// initial centroids coincide, every k must still give k colors
+	for _, seed := range []int64{1, 2, 3, 4, 5} {
+		for _, method := range []AutoKMethod{AutoKSilhouette, AutoKElbow} {
+			result, err := ExtractAutoK(context.Background(), img, Options{Seed: seed}, AutoKOptions{MinK: 2, MaxK: 8, Method: method})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if result.K != 3 || result.Palette.Count() != 3 {
+				t.Errorf("method %d, seed %d: expected k = 3 with 3 colors, got k = %d with %d colors (scores: %v)", method, seed, result.K, result.Palette.Count(), result.Scores)
+			}
+			for _, score := range result.Scores {
+				if score.K > 3 {
+					t.Errorf("method %d, seed %d: expected k > 3 to be skipped, got %v", method, seed, result.Scores)
+				}
+			}
+		}
+	}
+
+	// MinK larger than the number of distinct colors is an error
+	if _, err := ExtractAutoK(context.Background(), img, Options{Seed: 1}, AutoKOptions{MinK: 4, MaxK: 8}); err == nil {
+		t.Errorf("expected an error for min k above the number of colors")
+	}
+}
+
+func TestExtractAutoKOptions(t *testing.T) {
+	img := loadTestImage(t, "testdata/resized.jpg")
+
+	invalid := []AutoKOptions{
+		{MinK: -1},
+		{MinK: 5, MaxK: 4},
+		{Method: AutoKMethod(-1)},
+	}
+	for _, auto := range invalid {
+		if _, err := ExtractAutoK(context.Background(), img, Options{}, auto); err == nil {
+			t.Errorf("expected an error for %+v", auto)
+		}
+	}
+
+	result, err := ExtractAutoK(context.Background(), img, Options{Seed: 1}, AutoKOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(result.Scores) != DefaultMaxK-DefaultMinK+1 {
+		t.Errorf("expected %d scores, got %d", DefaultMaxK-DefaultMinK+1, len(result.Scores))
+	}
+	if result.K < DefaultMinK || result.K > DefaultMaxK {
+		t.Errorf("expected k in [%d, %d], got %d", DefaultMinK, DefaultMaxK, result.K)
+	}
+
+	// k values beyond the number of colors are skipped
+	tiny := blocksImage([]color.Color{color.Black, color.White}, []int{1, 1})
+	result, err = ExtractAutoK(context.Background(), tiny, Options{HistogramBits: DefaultHistogramBits}, AutoKOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(result.Scores) != 1 || result.K != 2 {
+		t.Errorf("expected a single score for k = 2, got %v", result.Scores)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExtractAutoK(ctx, img, Options{}, AutoKOptions{}); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestElbow(t *testing.T) {
+	testCases := []struct {
+		inertias []float64
+		expected int
+	}{
+		{[]float64{100, 40, 10, 8, 7, 6}, 2},
+		{[]float64{100, 99, 98, 1}, 0},
+		{[]float64{100, 99, 98, 0}, 3},
+		{[]float64{100, 40, 0, 0}, 2},
+		{[]float64{100}, 0},
+		{[]float64{0, 0, 0}, 0},
+	}
+	for _, tc := range testCases {
+		scores := make([]AutoKScore, len(tc.inertias))
+		for i, inertia := range tc.inertias {
+			scores[i] = AutoKScore{K: i + 1, Inertia: inertia}
+		}
+		if result := elbow(scores); result != tc.expected {
+			t.Errorf("expected elbow at %d for %v, got %d", tc.expected, tc.inertias, result)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -15,16 +16,103 @@ import (
 	"os"
 
 	"github.com/mccutchen/palettor"
-	"github.com/nfnt/resize"
 	"github.com/pkg/profile"
 )
 
+var algorithms = map[string]func(palettor.Options) palettor.Extractor{
+	"kmeans":    func(opts palettor.Options) palettor.Extractor { return palettor.KMeans{Options: opts} },
+	"mediancut": func(opts palettor.Options) palettor.Extractor { return palettor.MedianCut{Options: opts} },
+	"octree":    func(opts palettor.Options) palettor.Extractor { return palettor.Octree{Options: opts} },
+}
+
+var autoKMethods = map[string]palettor.AutoKMethod{
+	"silhouette": palettor.AutoKSilhouette,
+	"elbow":      palettor.AutoKElbow,
+}
+
+var initMethods = map[string]palettor.InitMethod{
+	"random":   palettor.InitRandom,
+	"kmeans++": palettor.InitKMeansPlusPlus,
+}
+
+var centroidMethods = map[string]palettor.CentroidMethod{
+	"medoid": palettor.CentroidMedoid,
+	"mean":   palettor.CentroidMean,
+}
+
+var emptyClusterPolicies = map[string]palettor.EmptyClusterPolicy{
+	"drop":   palettor.EmptyDrop,
+	"reseed": palettor.EmptyReseedFarthest,
+	"split":  palettor.EmptySplitLargest,
+}
+
+var alphaPolicies = map[string]palettor.AlphaPolicy{
+	"ignore":    palettor.AlphaIgnore,
+	"skip":      palettor.AlphaSkip,
+	"weight":    palettor.AlphaWeight,
+	"composite": palettor.AlphaComposite,
+}
+
+var samplingMethods = map[string]palettor.SamplingMethod{
+	"grid":   palettor.SampleGrid,
+	"random": palettor.SampleRandom,
+}
+
+var sortOrders = map[string]palettor.SortOrder{
+	"weight":     palettor.ByWeightDescending,
+	"weight-asc": palettor.ByWeight,
+	"hue":        palettor.ByHue,
+	"luminance":  palettor.ByLuminance,
+	"walk":       palettor.ByPaletteWalk,
+}
+
+var formats = map[string]func(io.Writer, *palettor.Palette, ...palettor.SortOrder) error{
+	"json": func(w io.Writer, p *palettor.Palette, order ...palettor.SortOrder) error {
+		return json.NewEncoder(w).Encode(palettor.OrderedPalette{Palette: p, Order: order})
+	},
+	"ase":  palettor.EncodeASE,
+	"aco":  palettor.EncodeACO,
+	"gpl":  palettor.EncodeGPL,
+	"css":  palettor.EncodeCSS,
+	"scss": palettor.EncodeSCSS,
+}
+
+var metrics = map[string]palettor.Metric{
+	"euclidean": palettor.EuclideanRGB,
+	"redmean":   palettor.Redmean,
+	"cie76":     palettor.CIE76,
+	"cie94":     palettor.CIE94,
+	"ciede2000": palettor.CIEDE2000,
+}
+
 func main() {
 	var (
 		k          = flag.Int("k", 3, "Palette size")
+		algName    = flag.String("algorithm", "kmeans", "Palette extraction algorithm: kmeans, mediancut or octree")
+		autoKName  = flag.String("auto-k", "", "Pick the best palette size between -min-k and -max-k using silhouette or elbow, ignoring -k (kmeans only; default: off)")
+		minK       = flag.Int("min-k", palettor.DefaultMinK, "Smallest palette size to try with -auto-k")
+		maxK       = flag.Int("max-k", palettor.DefaultMaxK, "Largest palette size to try with -auto-k")
 		maxIters   = flag.Int("max", 500, "Maximum k-means iterations")
-		jsonOutput = flag.Bool("json", false, "Output color palette in JSON format")
-		noResize   = flag.Bool("no-resize", false, "Do not resize input image before processing")
+		batchSize  = flag.Int("batch-size", 0, "Use mini-batch k-means with batches of this many pixels (default: off)")
+		tolerance  = flag.Float64("tolerance", 0, "Distance, in -metric units, within which k-means centroids count as converged")
+		seed       = flag.Int64("seed", 0, "Random seed, for reproducible output (default: random)")
+		restarts   = flag.Int("restarts", 1, "Number of k-means runs, keeping the palette with the lowest inertia")
+		concurrent = flag.Bool("concurrent-restarts", false, "Run k-means restarts concurrently")
+		initName   = flag.String("init", "random", "Centroid initialization method: random or kmeans++")
+		centroid   = flag.String("centroid", "medoid", "Centroid method: medoid or mean")
+		emptyName  = flag.String("empty", "drop", "Empty k-means cluster handling: drop, reseed (at the farthest pixel) or split (the largest cluster)")
+		alphaName  = flag.String("alpha", "ignore", "Transparent pixel handling: ignore, skip, weight or composite (over white)")
+		alphaMin   = flag.Float64("alpha-threshold", 0, "Alpha at or below which pixels are skipped with -alpha=skip")
+		metricName = flag.String("metric", "euclidean", "Color distance metric: euclidean, redmean, cie76, cie94 or ciede2000")
+		jsonOutput = flag.Bool("json", false, "Output color palette in JSON format, with hex, RGB, HSL and Lab colors (same as -format=json)")
+		formatName = flag.String("format", "image", "Output format: image (the input image with the palette drawn over it), json, ase, aco, gpl, css or scss")
+		sortName   = flag.String("sort", "weight-asc", "Palette order in every output format: weight (most dominant first), weight-asc, hue, luminance or walk")
+		noResize   = flag.Bool("no-resize", false, "Cluster every pixel of the input image, ignoring -max-pixels")
+		maxPixels  = flag.Int("max-pixels", palettor.DefaultMaxPixels, "Maximum number of pixels to sample from the input image (0 means no limit)")
+		sampleName = flag.String("sample", "grid", "Pixel sampling method for large images: grid or random")
+		timeout    = flag.Duration("timeout", 0, "Maximum time to spend extracting the palette (default: no limit)")
+		histBits   = flag.Int("histogram-bits", 0, "Bucket pixels into a histogram with this many bits per channel before clustering (default: off)")
+		workers    = flag.Int("workers", 0, "Number of goroutines to use for clustering (default: GOMAXPROCS)")
 		doProfile  = flag.Bool("profile", false, "Capture profile")
 	)
 	flag.Usage = func() {
@@ -33,6 +121,58 @@ func main() {
 	}
 	flag.Parse()
 
+	newExtractor, ok := algorithms[*algName]
+	if !ok {
+		log.Fatalf("Unknown algorithm: %q", *algName)
+	}
+	var autoKMethod palettor.AutoKMethod
+	if *autoKName != "" {
+		if autoKMethod, ok = autoKMethods[*autoKName]; !ok {
+			log.Fatalf("Unknown auto k method: %q", *autoKName)
+		}
+		if *algName != "kmeans" {
+			log.Fatalf("-auto-k is only supported by the kmeans algorithm")
+		}
+	}
+	initMethod, ok := initMethods[*initName]
+	if !ok {
+		log.Fatalf("Unknown init method: %q", *initName)
+	}
+	centroidMethod, ok := centroidMethods[*centroid]
+	if !ok {
+		log.Fatalf("Unknown centroid method: %q", *centroid)
+	}
+	emptyClusters, ok := emptyClusterPolicies[*emptyName]
+	if !ok {
+		log.Fatalf("Unknown empty cluster policy: %q", *emptyName)
+	}
+	metric, ok := metrics[*metricName]
+	if !ok {
+		log.Fatalf("Unknown metric: %q", *metricName)
+	}
+	alphaPolicy, ok := alphaPolicies[*alphaName]
+	if !ok {
+		log.Fatalf("Unknown alpha policy: %q", *alphaName)
+	}
+	samplingMethod, ok := samplingMethods[*sampleName]
+	if !ok {
+		log.Fatalf("Unknown sampling method: %q", *sampleName)
+	}
+	sortOrder, ok := sortOrders[*sortName]
+	if !ok {
+		log.Fatalf("Unknown sort order: %q", *sortName)
+	}
+	if *jsonOutput {
+		*formatName = "json"
+	}
+	encode, ok := formats[*formatName]
+	if !ok && *formatName != "image" {
+		log.Fatalf("Unknown output format: %q", *formatName)
+	}
+	if *noResize {
+		*maxPixels = 0
+	}
+
 	var (
 		input io.Reader
 		err   error
@@ -52,29 +192,59 @@ func main() {
 		log.Fatalf("Error decoding image: %s", err)
 	}
 
-	// Get the image down to a more manageable size
-	if !*noResize {
-		img = resize.Thumbnail(200, 200, img, resize.NearestNeighbor)
-	}
-
 	// Only start profiling after the image is loaded
 	if *doProfile {
 		defer profile.Start().Stop()
 	}
 
-	palette, err := palettor.Extract(*k, *maxIters, img)
+	opts := palettor.Options{
+		K:                  *k,
+		MaxIterations:      *maxIters,
+		Tolerance:          *tolerance,
+		BatchSize:          *batchSize,
+		Seed:               *seed,
+		Init:               initMethod,
+		Metric:             metric,
+		Centroid:           centroidMethod,
+		EmptyClusters:      emptyClusters,
+		Alpha:              alphaPolicy,
+		AlphaThreshold:     *alphaMin,
+		Timeout:            *timeout,
+		Workers:            *workers,
+		HistogramBits:      *histBits,
+		MaxPixels:          *maxPixels,
+		Sampling:           samplingMethod,
+		Restarts:           *restarts,
+		ConcurrentRestarts: *concurrent,
+	}
+
+	var palette *palettor.Palette
+	if *autoKName != "" {
+		var result *palettor.AutoKResult
+		result, err = palettor.ExtractAutoK(context.Background(), img, opts, palettor.AutoKOptions{
+			MinK:   *minK,
+			MaxK:   *maxK,
+			Method: autoKMethod,
+		})
+		if err == nil {
+			log.Printf("Picked palette size %d", result.K)
+			palette = result.Palette
+		}
+	} else {
+		palette, err = newExtractor(opts).Extract(context.Background(), img)
+	}
 	if err != nil {
 		log.Fatalf("Error extracing color palette: %s", err)
 	}
 
-	if *jsonOutput {
-		if err := json.NewEncoder(os.Stdout).Encode(palette.Entries()); err != nil {
-			log.Fatalf("Error encoding JSON: %s", err)
+	if encode != nil {
+		if err := encode(os.Stdout, palette, sortOrder); err != nil {
+			log.Fatalf("Error encoding palette: %s", err)
 		}
 		return
 	}
 
-	if err := drawPalette(os.Stdout, img, palette, format); err != nil {
+	if err := drawPalette(os.Stdout, img, palette.Entries(sortOrder), format); err != nil {
 		log.Fatalf("Error encoding palette: %s", err)
 	}
 }
@@ -103,7 +273,7 @@ func loadImage(src io.Reader) (image.Image, string, error) {
 }
 
 // Draw a palette over the bottom 10% of an image
-func drawPalette(dst io.Writer, img image.Image, palette *palettor.Palette, format string) error {
+func drawPalette(dst io.Writer, img image.Image, entries []palettor.Entry, format string) error {
 	drawImg := img.(draw.Image)
 
 	imgWidth := img.Bounds().Dx()
@@ -113,7 +283,7 @@ func drawPalette(dst io.Writer, img image.Image, palette *palettor.Palette, form
 	yOffset := imgHeight - paletteHeight
 	xOffset := 0
 
-	for _, entry := range palette.Entries() {
+	for _, entry := range entries {
 		colorWidth := int(math.Ceil(float64(imgWidth) * entry.Weight))
 		bounds := image.Rect(xOffset, yOffset, xOffset+colorWidth, yOffset+paletteHeight)
 		draw.Draw(drawImg, bounds, &image.Uniform{entry.Color}, image.Point{}, draw.Src)
@@ -0,0 +1,148 @@
+package palettor
+
+import (
+	"image/color"
+	"math"
+)
+
+// A ColorSpace is a color space in which colors are clustered. Each color is
+// converted into a point in the color space once, before clustering, and the
+// distance between two colors is the Euclidean distance between their
+// points.
+type ColorSpace int
+
+// Supported color spaces
+const (
+	// RGB treats a color's 16-bit red, green and blue channels as its
+	// coordinates. It is cheap, but not perceptually uniform.
+	RGB ColorSpace = iota
+
+	// Lab is the CIELAB color space with a D65 white point, which is designed
+	// so that the distance between two colors approximates how different
+	// they look.
+	//
+	// https://en.wikipedia.org/wiki/CIELAB_color_space
+	Lab
+)
+
+// A point is a color's coordinates in a ColorSpace. Alpha is not part of a
+// color's coordinates.
+type point = [3]float64
+
+// D65 reference white, in XYZ coordinates
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// rgbPoint converts 16-bit RGB channels to coordinates in the color space.
+func (s ColorSpace) rgbPoint(r, g, b uint32) point {
+	if s == Lab {
+		return rgbToLab(r, g, b)
+	}
+	return point{float64(r), float64(g), float64(b)}
+}
+
+// color converts coordinates in the color space back to a color with the given
+// 16-bit alpha.
+func (s ColorSpace) color(p point, alpha uint32) color.Color {
+	if s == Lab {
+		p = labToRGB(p)
+	}
+	return color.RGBA64{
+		R: toChannel(p[0], alpha),
+		G: toChannel(p[1], alpha),
+		B: toChannel(p[2], alpha),
+		A: uint16(alpha),
+	}
+}
+
+// toChannel rounds a 16-bit channel value, clamping it to the given alpha so
+// that the result is a valid alpha-premultiplied value.
+func toChannel(c float64, alpha uint32) uint16 {
+	c = math.Round(c)
+	if c < 0 {
+		return 0
+	}
+	if c > float64(alpha) {
+		return uint16(alpha)
+	}
+	return uint16(c)
+}
+
+// rgbToLab converts 16-bit sRGB channels to CIELAB coordinates.
+func rgbToLab(r, g, b uint32) point {
+	lr := linearize(float64(r) / 0xffff)
+	lg := linearize(float64(g) / 0xffff)
+	lb := linearize(float64(b) / 0xffff)
+
+	x := 0.4124564*lr + 0.3575761*lg + 0.1804375*lb
+	y := 0.2126729*lr + 0.7151522*lg + 0.0721750*lb
+	z := 0.0193339*lr + 0.1191920*lg + 0.9503041*lb
+
+	fx := labF(x / whiteX)
+	fy := labF(y / whiteY)
+	fz := labF(z / whiteZ)
+	return point{
+		116*fy - 16,
+		500 * (fx - fy),
+		200 * (fy - fz),
+	}
+}
+
+// labToRGB converts CIELAB coordinates to 16-bit sRGB channels, which may be
+// out of range.
+func labToRGB(p point) point {
+	fy := (p[0] + 16) / 116
+	fx := fy + p[1]/500
+	fz := fy - p[2]/200
+
+	x := whiteX * labFInverse(fx)
+	y := whiteY * labFInverse(fy)
+	z := whiteZ * labFInverse(fz)
+
+	lr := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	lg := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	lb := 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return point{
+		delinearize(lr) * 0xffff,
+		delinearize(lg) * 0xffff,
+		delinearize(lb) * 0xffff,
+	}
+}
+
+// linearize undoes the sRGB gamma curve for a channel in the range [0, 1].
+func linearize(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// delinearize applies the sRGB gamma curve to a linear channel value.
+func delinearize(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
+
+// labF is the nonlinear transfer function used to compute CIELAB coordinates
+// from relative XYZ coordinates.
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
+
+// labFInverse is the inverse of labF.
+func labFInverse(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta {
+		return t * t * t
+	}
+	return 3 * delta * delta * (t - 4.0/29)
+}
@@ -0,0 +1,76 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestLabPoint(t *testing.T) {
+	testCases := []struct {
+		color    color.Color
+		expected point
+	}{
+		{color.RGBA{0, 0, 0, 255}, point{0, 0, 0}},
+		{color.RGBA{255, 255, 255, 255}, point{100, 0, 0}},
+		{color.RGBA{255, 0, 0, 255}, point{53.2408, 80.0925, 67.2032}},
+		{color.RGBA{0, 255, 0, 255}, point{87.7347, -86.1827, 83.1793}},
+		{color.RGBA{0, 0, 255, 255}, point{32.2970, 79.1875, -107.8602}},
+		{color.RGBA{128, 128, 128, 255}, point{53.5850, 0, 0}},
+	}
+	for _, tc := range testCases {
+		actual := testPoint(Lab, tc.color)
+		for i := range actual {
+			if math.Abs(actual[i]-tc.expected[i]) > 0.01 {
+				t.Errorf("Lab coordinates of %v: expected %v, got %v", tc.color, tc.expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestRGBPoint(t *testing.T) {
+	expected := point{0xffff, 0x8080, 0}
+	if actual := testPoint(RGB, color.RGBA{255, 128, 0, 255}); actual != expected {
+		t.Errorf("RGB coordinates: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestColorSpaceRoundTrip(t *testing.T) {
+	colors := []color.Color{
+		color.RGBA{0, 0, 0, 255},
+		color.RGBA{255, 255, 255, 255},
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{18, 52, 86, 255},
+		color.RGBA{200, 180, 20, 255},
+	}
+	for _, space := range []ColorSpace{RGB, Lab} {
+		for _, c := range colors {
+			expected := color.RGBA64Model.Convert(c)
+			actual := space.color(testPoint(space, c), 0xffff)
+			if actual != expected {
+				t.Errorf("color space %d: expected %v to round-trip, got %v", space, expected, actual)
+			}
+		}
+	}
+}
+
+func TestClusterLab(t *testing.T) {
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	palette, err := clusterColors(context.Background(), colors, Options{K: 4, MaxIterations: 100, Seed: 1, ColorSpace: Lab})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != 4 {
+		t.Errorf("expected 4 colors, got %d", palette.Count())
+	}
+
+	var total float64
+	for _, entry := range palette.Entries() {
+		total += entry.Weight
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("expected weights to sum to 1, got %v", total)
+	}
+}
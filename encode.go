@@ -0,0 +1,145 @@
+package palettor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"unicode/utf16"
+)
+
+// The encoders below write a Palette's colors in the given order, as by
+// Palette.Entries, or from the most to the least dominant if no order is
+// given. Each color is named after its hex string. None of the formats can
+// represent weights, and only CSS and SCSS can represent alpha, so the other
+// formats are given colors without their alpha.
+
+// EncodeASE writes a Palette in the Adobe Swatch Exchange (.ase) format used
+// by Illustrator, InDesign and Photoshop.
+func EncodeASE(w io.Writer, p *Palette, order ...SortOrder) error {
+	entries := encodeEntries(p, order)
+
+	var buf bytes.Buffer
+	bw := &binaryWriter{w: &buf}
+	bw.write([]byte("ASEF"))
+	bw.write([2]uint16{1, 0})
+	bw.write(uint32(len(entries)))
+	for _, entry := range entries {
+		name := utf16Name(hexString(opaque(normalizeColor(entry.Color))))
+		c := color.NRGBA64Model.Convert(entry.Color).(color.NRGBA64)
+
+		// Block type 1 is a color, and color type 2 is a normal (i.e. not a
+		// global or spot) color
+		bw.write(uint16(1))
+		bw.write(uint32(2 + 2*len(name) + 4 + 3*4 + 2))
+		bw.write(uint16(len(name)))
+		bw.write(name)
+		bw.write([]byte("RGB "))
+		bw.write([3]float32{
+			float32(c.R) / 0xffff,
+			float32(c.G) / 0xffff,
+			float32(c.B) / 0xffff,
+		})
+		bw.write(uint16(2))
+	}
+	if bw.err != nil {
+		return bw.err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// EncodeACO writes a Palette in the Photoshop color swatch (.aco) format. Both
+// the original unnamed version 1 swatches and the named version 2 swatches
+// are written, as Photoshop does.
+func EncodeACO(w io.Writer, p *Palette, order ...SortOrder) error {
+	entries := encodeEntries(p, order)
+
+	var buf bytes.Buffer
+	bw := &binaryWriter{w: &buf}
+	for version := uint16(1); version <= 2; version++ {
+		bw.write([2]uint16{version, uint16(len(entries))})
+		for _, entry := range entries {
+			// Color space 0 is RGB, with the fourth channel unused
+			c := color.NRGBA64Model.Convert(entry.Color).(color.NRGBA64)
+			bw.write([5]uint16{0, c.R, c.G, c.B, 0})
+			if version == 2 {
+				name := utf16Name(hexString(opaque(normalizeColor(entry.Color))))
+				bw.write(uint32(len(name)))
+				bw.write(name)
+			}
+		}
+	}
+	if bw.err != nil {
+		return bw.err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// EncodeGPL writes a Palette in the GIMP palette (.gpl) format, which is also
+// used by Inkscape and Krita.
+func EncodeGPL(w io.Writer, p *Palette, order ...SortOrder) error {
+	var buf bytes.Buffer
+	buf.WriteString("GIMP Palette\nName: palettor\n#\n")
+	for _, entry := range encodeEntries(p, order) {
+		c := color.NRGBAModel.Convert(entry.Color).(color.NRGBA)
+		fmt.Fprintf(&buf, "%3d %3d %3d\t%s\n", c.R, c.G, c.B, hexString(opaque(normalizeColor(entry.Color))))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// EncodeCSS writes a Palette as CSS custom properties named --color-1,
+// --color-2 and so on, on the :root selector.
+func EncodeCSS(w io.Writer, p *Palette, order ...SortOrder) error {
+	var buf bytes.Buffer
+	buf.WriteString(":root {\n")
+	for i, entry := range encodeEntries(p, order) {
+		fmt.Fprintf(&buf, "  --color-%d: %s;\n", i+1, hexString(entry.Color))
+	}
+	buf.WriteString("}\n")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// EncodeSCSS writes a Palette as SCSS variables named $color-1, $color-2 and
+// so on.
+func EncodeSCSS(w io.Writer, p *Palette, order ...SortOrder) error {
+	var buf bytes.Buffer
+	for i, entry := range encodeEntries(p, order) {
+		fmt.Fprintf(&buf, "$color-%d: %s;\n", i+1, hexString(entry.Color))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// encodeEntries returns the entries of a Palette in the given order, or from
+// the most to the least dominant if no order is given.
+func encodeEntries(p *Palette, order []SortOrder) []Entry {
+	if len(order) == 0 {
+		order = []SortOrder{ByWeightDescending}
+	}
+	return p.Entries(order...)
+}
+
+// A binaryWriter writes big-endian binary values, as used by the Adobe
+// formats, keeping the first error and skipping every write after it.
+type binaryWriter struct {
+	w   io.Writer
+	err error
+}
+
+// write writes a value with binary.Write, unless an earlier write failed.
+func (bw *binaryWriter) write(v interface{}) {
+	if bw.err == nil {
+		bw.err = binary.Write(bw.w, binary.BigEndian, v)
+	}
+}
+
+// utf16Name encodes a swatch name as null-terminated UTF-16, as used by the
+// Adobe formats.
+func utf16Name(name string) []uint16 {
+	return append(utf16.Encode([]rune(name)), 0)
+}
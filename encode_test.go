@@ -0,0 +1,214 @@
+package palettor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"math"
+	"testing"
+)
+
+func encodeTestPalette() *Palette {
+	return newPalette([]Entry{
+		{color.RGBA{0, 0, 255, 255}, 0.25},
+		{color.RGBA{255, 0, 0, 255}, 0.75},
+		{color.NRGBA{0, 255, 0, 0x80}, 0},
+	}, nil, 0, 1, true)
+}
+
+func TestEncodeTextFormats(t *testing.T) {
+	testCases := []struct {
+		name     string
+		encode   func(*bytes.Buffer, *Palette) error
+		expected string
+	}{
+		{
+			"gpl",
+			func(buf *bytes.Buffer, p *Palette) error { return EncodeGPL(buf, p) },
+			"GIMP Palette\nName: palettor\n#\n255   0   0\t#ff0000\n  0   0 255\t#0000ff\n  0 255   0\t#00ff00\n",
+		},
+		{
+			"css",
+			func(buf *bytes.Buffer, p *Palette) error { return EncodeCSS(buf, p) },
+			":root {\n  --color-1: #ff0000;\n  --color-2: #0000ff;\n  --color-3: #00ff0080;\n}\n",
+		},
+		{
+			"scss",
+			func(buf *bytes.Buffer, p *Palette) error { return EncodeSCSS(buf, p) },
+			"$color-1: #ff0000;\n$color-2: #0000ff;\n$color-3: #00ff0080;\n",
+		},
+		{
+			"gpl by hue",
+			func(buf *bytes.Buffer, p *Palette) error { return EncodeGPL(buf, p, ByHue) },
+			"GIMP Palette\nName: palettor\n#\n255   0   0\t#ff0000\n  0 255   0\t#00ff00\n  0   0 255\t#0000ff\n",
+		},
+		{
+			"css by weight",
+			func(buf *bytes.Buffer, p *Palette) error { return EncodeCSS(buf, p, ByWeight) },
+			":root {\n  --color-1: #00ff0080;\n  --color-2: #0000ff;\n  --color-3: #ff0000;\n}\n",
+		},
+		{
+			"scss by luminance",
+			func(buf *bytes.Buffer, p *Palette) error { return EncodeSCSS(buf, p, ByLuminance) },
+			"$color-1: #0000ff;\n$color-2: #00ff0080;\n$color-3: #ff0000;\n",
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := tc.encode(&buf, encodeTestPalette()); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.name, err)
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, buf.String())
+		}
+	}
+}
+
+func TestEncodeASE(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeASE(&buf, encodeTestPalette()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	read := func(v interface{}) {
+		if err := binary.Read(&buf, binary.BigEndian, v); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	var header struct {
+		Signature [4]byte
+		Version   [2]uint16
+		Blocks    uint32
+	}
+	read(&header)
+	if string(header.Signature[:]) != "ASEF" || header.Version != [2]uint16{1, 0} || header.Blocks != 3 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	expected := [][3]float32{{1, 0, 0}, {0, 0, 1}, {0, 1, 0}}
+	for i, rgb := range expected {
+		var block struct {
+			Type       uint16
+			Length     uint32
+			NameLength uint16
+			Name       [8]uint16
+			Model      [4]byte
+			RGB        [3]float32
+			ColorType  uint16
+		}
+		read(&block)
+		if block.Type != 1 || block.Length != 36 || block.NameLength != 8 || block.Name[0] != '#' || block.Name[7] != 0 {
+			t.Errorf("block %d: unexpected block %+v", i, block)
+		}
+		if string(block.Model[:]) != "RGB " || block.RGB != rgb || block.ColorType != 2 {
+			t.Errorf("block %d: expected RGB %v, got %+v", i, rgb, block)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no trailing data, got %d bytes", buf.Len())
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestEncodeErrors(t *testing.T) {
+	encoders := map[string]func(io.Writer, *Palette, ...SortOrder) error{
+		"ase":  EncodeASE,
+		"aco":  EncodeACO,
+		"gpl":  EncodeGPL,
+		"css":  EncodeCSS,
+		"scss": EncodeSCSS,
+	}
+	for name, encode := range encoders {
+		if err := encode(failingWriter{}, encodeTestPalette()); err == nil {
+			t.Errorf("%s: expected write error", name)
+		}
+	}
+
+	// A failed binary write is reported, and later writes are skipped
+	bw := &binaryWriter{w: failingWriter{}}
+	bw.write(uint16(1))
+	bw.write(uint16(2))
+	if bw.err == nil || bw.err.Error() != "write failed" {
+		t.Errorf("expected write error, got %v", bw.err)
+	}
+}
+
+func TestEncodeOrder(t *testing.T) {
+	encoders := map[string]func(io.Writer, *Palette, ...SortOrder) error{
+		"ase":  EncodeASE,
+		"aco":  EncodeACO,
+		"gpl":  EncodeGPL,
+		"css":  EncodeCSS,
+		"scss": EncodeSCSS,
+	}
+	for name, encode := range encoders {
+		encoded := func(order ...SortOrder) string {
+			var buf bytes.Buffer
+			if err := encode(&buf, encodeTestPalette(), order...); err != nil {
+				t.Fatalf("%s: unexpected error: %s", name, err)
+			}
+			return buf.String()
+		}
+		if encoded() != encoded(ByWeightDescending) {
+			t.Errorf("%s: expected the most dominant color first by default", name)
+		}
+		if encoded() == encoded(ByHue) {
+			t.Errorf("%s: expected the given order to be used", name)
+		}
+	}
+}
+
+func TestEncodeACO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeACO(&buf, encodeTestPalette()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	read := func(v interface{}) {
+		if err := binary.Read(&buf, binary.BigEndian, v); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	expected := [][5]uint16{
+		{0, math.MaxUint16, 0, 0, 0},
+		{0, 0, 0, math.MaxUint16, 0},
+		{0, 0, math.MaxUint16, 0, 0},
+	}
+	for version := uint16(1); version <= 2; version++ {
+		var header [2]uint16
+		read(&header)
+		if header != [2]uint16{version, 3} {
+			t.Fatalf("expected version %d header, got %v", version, header)
+		}
+		for i, swatch := range expected {
+			var got [5]uint16
+			read(&got)
+			if got != swatch {
+				t.Errorf("version %d, swatch %d: expected %v, got %v", version, i, swatch, got)
+			}
+			if version == 2 {
+				var name struct {
+					Length uint32
+					Name   [8]uint16
+				}
+				read(&name)
+				if name.Length != 8 || name.Name[0] != '#' || name.Name[7] != 0 {
+					t.Errorf("swatch %d: unexpected name %v", i, name)
+				}
+			}
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no trailing data, got %d bytes", buf.Len())
+	}
+}
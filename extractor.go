@@ -0,0 +1,46 @@
+package palettor
+
+import (
+	"context"
+	"image"
+)
+
+// An Extractor extracts the dominant colors of an image as a Palette. It
+// stops early and returns ctx.Err() if ctx is done before extraction
+// finishes.
+type Extractor interface {
+	Extract(ctx context.Context, img image.Image) (*Palette, error)
+}
+
+// KMeans is an Extractor which uses k-means clustering, as configured by its
+// Options. See ExtractWithOptions.
+type KMeans struct {
+	Options
+}
+
+// Extract implements Extractor.
+func (e KMeans) Extract(ctx context.Context, img image.Image) (*Palette, error) {
+	return extract(ctx, img, e.Options, clusterColors)
+}
+
+// MedianCut is an Extractor which uses median cut quantization, as configured
+// by its Options. See ExtractMedianCut.
+type MedianCut struct {
+	Options
+}
+
+// Extract implements Extractor.
+func (e MedianCut) Extract(ctx context.Context, img image.Image) (*Palette, error) {
+	return extract(ctx, img, e.Options, medianCut)
+}
+
+// Octree is an Extractor which uses octree quantization, as configured by its
+// Options. See ExtractOctree.
+type Octree struct {
+	Options
+}
+
+// Extract implements Extractor.
+func (e Octree) Extract(ctx context.Context, img image.Image) (*Palette, error) {
+	return extract(ctx, img, e.Options, octreeQuantize)
+}
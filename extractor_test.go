@@ -0,0 +1,90 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestExtractors(t *testing.T) {
+	img := loadTestImage(t, "testdata/resized.jpg")
+	opts := Options{K: 3, Seed: 1, Init: InitKMeansPlusPlus}
+
+	testCases := []struct {
+		name      string
+		extractor Extractor
+		invalid   Extractor
+		algorithm algorithm
+	}{
+		{"kmeans", KMeans{opts}, KMeans{}, clusterColors},
+		{"median cut", MedianCut{opts}, MedianCut{}, medianCut},
+		{"octree", Octree{opts}, Octree{}, octreeQuantize},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			palette, err := tc.extractor.Extract(context.Background(), img)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if palette.Count() != 3 {
+				t.Errorf("expected 3 colors, got %d", palette.Count())
+			}
+			var total float64
+			for _, entry := range palette.Entries() {
+				total += entry.Weight
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Errorf("expected weights to sum to 1, got %v", total)
+			}
+
+			again, err := tc.extractor.Extract(context.Background(), img)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(palette.Entries(), again.Entries()) {
+				t.Errorf("expected identical palettes, got %v and %v", palette.Entries(), again.Entries())
+			}
+
+			if _, err := tc.invalid.Extract(context.Background(), img); err == nil {
+				t.Errorf("k is required, expected an error")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := tc.extractor.Extract(ctx, img); err != context.Canceled {
+				t.Errorf("expected %v, got %v", context.Canceled, err)
+			}
+
+			// The algorithms are given validated options, as by extract
+			small, err := opts.withDefaults()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			small.K = 4
+			colors := rgba64s(opaqueBlack, opaqueWhite, opaqueRed)
+			if _, err := tc.algorithm(context.Background(), colors, small); err == nil {
+				t.Errorf("too few colors should result in an error")
+			}
+
+			// Every distinct color ends up in its own cluster
+			colors = rgba64s(opaqueBlack, opaqueWhite, opaqueRed, opaqueBlue, opaqueBlack, opaqueBlack)
+			palette, err = tc.algorithm(context.Background(), colors, small)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if palette.Count() != 4 {
+				t.Fatalf("expected 4 colors, got %d", palette.Count())
+			}
+			if palette.Weight(opaqueBlack) != 0.5 {
+				t.Errorf("expected weight of black to be 0.5, got %v", palette.Weight(opaqueBlack))
+			}
+			for _, c := range []color.Color{opaqueWhite, opaqueRed, opaqueBlue} {
+				if w := palette.Weight(c); math.Abs(w-1.0/6) > 1e-9 {
+					t.Errorf("expected weight of %v to be 1/6, got %v", c, w)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,4 @@ module github.com/mccutchen/palettor
 
 go 1.12
 
-require (
-	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
-	github.com/pkg/profile v1.6.0 // indirect
-)
+require github.com/pkg/profile v1.6.0
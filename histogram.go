@@ -0,0 +1,86 @@
+package palettor
+
+import "image/color"
+
+// DefaultHistogramBits is a reasonable number of bits per channel for
+// Options.HistogramBits, which reduces typical photos to a few thousand
+// distinct colors with little visible loss.
+const DefaultHistogramBits = 5
+
+// A bucket accumulates the weighted sum of a set of colors, such as the
+// pixels which fall into one cell of a color histogram.
+type bucket struct {
+	r, g, b, a float64
+	weight     float64
+}
+
+// add adds a color with the given weight to a bucket.
+func (b *bucket) add(c color.RGBA64, weight float64) {
+	b.r += weight * float64(c.R)
+	b.g += weight * float64(c.G)
+	b.b += weight * float64(c.B)
+	b.a += weight * float64(c.A)
+	b.weight += weight
+}
+
+// merge adds the colors in another bucket to a bucket.
+func (b *bucket) merge(other *bucket) {
+	b.r += other.r
+	b.g += other.g
+	b.b += other.b
+	b.a += other.a
+	b.weight += other.weight
+}
+
+// color returns the weighted mean of the colors in a non-empty bucket.
+func (b *bucket) color() color.RGBA64 {
+	return color.RGBA64{
+		R: uint16(b.r/b.weight + 0.5),
+		G: uint16(b.g/b.weight + 0.5),
+		B: uint16(b.b/b.weight + 0.5),
+		A: uint16(b.a/b.weight + 0.5),
+	}
+}
+
+// quantize buckets the given colors into a histogram with the given number of
+// bits per channel, returning the weighted mean color of each non-empty
+// bucket, the total weight of the colors in each bucket, and the number of
+// colors in each bucket. The weights of the given colors may be nil if every
+// color has weight 1.
+//
+// Buckets are returned in the order in which they are first encountered, so
+// the result is deterministic.
+func quantize(colors []color.RGBA64, weights []float32, bits int) ([]color.RGBA64, []float32, []int32) {
+	shift := 16 - uint(bits)
+	indexes := make(map[uint32]int)
+	var buckets []bucket
+	var counts []int32
+	for i, c := range colors {
+		key := uint32(c.R>>shift)<<(3*uint(bits)) |
+			uint32(c.G>>shift)<<(2*uint(bits)) |
+			uint32(c.B>>shift)<<uint(bits) |
+			uint32(c.A>>shift)
+		index, found := indexes[key]
+		if !found {
+			index = len(buckets)
+			indexes[key] = index
+			buckets = append(buckets, bucket{})
+			counts = append(counts, 0)
+		}
+
+		w := 1.0
+		if weights != nil {
+			w = float64(weights[i])
+		}
+		buckets[index].add(c, w)
+		counts[index]++
+	}
+
+	result := make([]color.RGBA64, len(buckets))
+	resultWeights := make([]float32, len(buckets))
+	for i := range buckets {
+		result[i] = buckets[i].color()
+		resultWeights[i] = float32(buckets[i].weight)
+	}
+	return result, resultWeights, counts
+}
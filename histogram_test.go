@@ -0,0 +1,108 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestQuantize(t *testing.T) {
+	colors := []color.RGBA64{
+		{0x0000, 0x0000, 0x0000, 0xffff},
+		{0x0100, 0x0100, 0x0100, 0xffff},
+		{0xffff, 0x0000, 0x0000, 0xffff},
+		{0x0300, 0x0300, 0x0300, 0xffff},
+	}
+
+	// With 5 bits per channel, the dark grays share a bucket with black
+	result, weights, counts := quantize(colors, nil, 5)
+	expectedColors := []color.RGBA64{
+		{0x0155, 0x0155, 0x0155, 0xffff},
+		{0xffff, 0x0000, 0x0000, 0xffff},
+	}
+	if !reflect.DeepEqual(result, expectedColors) {
+		t.Errorf("expected colors %v, got %v", expectedColors, result)
+	}
+	if expectedWeights := []float32{3, 1}; !reflect.DeepEqual(weights, expectedWeights) {
+		t.Errorf("expected weights %v, got %v", expectedWeights, weights)
+	}
+	if expectedCounts := []int32{3, 1}; !reflect.DeepEqual(counts, expectedCounts) {
+		t.Errorf("expected counts %v, got %v", expectedCounts, counts)
+	}
+
+	// With 8 bits per channel, only identical colors would share a bucket
+	if result, _, _ := quantize(colors, nil, 8); len(result) != len(colors) {
+		t.Errorf("expected %d buckets, got %d", len(colors), len(result))
+	}
+
+	// Existing weights are carried over into the weighted mean
+	result, weights, counts = quantize(colors[:2], []float32{1, 3}, 5)
+	if expected := (color.RGBA64{0x00c0, 0x00c0, 0x00c0, 0xffff}); len(result) != 1 || result[0] != expected {
+		t.Errorf("expected weighted mean %v, got %v", expected, result)
+	}
+	if len(weights) != 1 || weights[0] != 4 {
+		t.Errorf("expected total weight 4, got %v", weights)
+	}
+
+	// Counts are numbers of pixels, regardless of weights
+	if len(counts) != 1 || counts[0] != 2 {
+		t.Errorf("expected count 2, got %v", counts)
+	}
+}
+
+func TestClusterHistogram(t *testing.T) {
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	opts := Options{K: 3, MaxIterations: 100, Seed: 1, Init: InitKMeansPlusPlus, Centroid: CentroidMean}
+	expected, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	opts.HistogramBits = DefaultHistogramBits
+	palette, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != expected.Count() {
+		t.Fatalf("expected %d colors, got %d", expected.Count(), palette.Count())
+	}
+
+	// The quantized palette should closely match the full palette
+	for _, entry := range palette.Entries() {
+		p := testPoint(Lab, entry.Color)
+		closest := math.Inf(1)
+		var closestWeight float64
+		for _, e := range expected.Entries() {
+			if d := CIE76.Distance(p, testPoint(Lab, e.Color)); d < closest {
+				closest, closestWeight = d, e.Weight
+			}
+		}
+		if closest > 5 || math.Abs(entry.Weight-closestWeight) > 0.05 {
+			t.Errorf("expected %v to closely match full palette %v", entry, expected.Entries())
+		}
+	}
+
+	// Too few buckets for k results in a smaller palette, not an error
+	colors = rgba64s(black, darkGray, white)
+	palette, err = clusterColors(context.Background(), colors, Options{K: 3, MaxIterations: 100, HistogramBits: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != 2 {
+		t.Errorf("expected 2 colors, got %d", palette.Count())
+	}
+}
+
+func BenchmarkExtractHistogram1080x1080(b *testing.B) {
+	img := loadTestImage(b, "testdata/original.jpg")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ExtractWithOptions(img, Options{K: 4, MaxIterations: 10, Seed: 1, HistogramBits: DefaultHistogramBits}); err != nil {
+			b.Error(err)
+		}
+	}
+}
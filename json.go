@@ -0,0 +1,193 @@
+package palettor
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// entryJSON is the JSON representation of an Entry. Hex is the canonical
+// representation of the color; the other representations are derived from it
+// for convenience, and are ignored when decoding.
+type entryJSON struct {
+	Hex    string        `json:"hex"`
+	RGB    rgbJSON       `json:"rgb"`
+	Alpha  float64       `json:"alpha"`
+	HSL    hslJSON       `json:"hsl"`
+	Lab    labJSON       `json:"lab"`
+	Weight float64       `json:"weight"`
+	Stats  *ClusterStats `json:"stats,omitempty"`
+}
+
+type rgbJSON struct {
+	R uint8 `json:"r"`
+	G uint8 `json:"g"`
+	B uint8 `json:"b"`
+}
+
+type hslJSON struct {
+	H float64 `json:"h"`
+	S float64 `json:"s"`
+	L float64 `json:"l"`
+}
+
+type labJSON struct {
+	L float64 `json:"l"`
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// paletteJSON is the JSON representation of a Palette.
+type paletteJSON struct {
+	Colors     []entryJSON `json:"colors"`
+	Inertia    float64     `json:"inertia"`
+	Iterations int         `json:"iterations"`
+	Converged  bool        `json:"converged"`
+	Restarts   int         `json:"restarts"`
+}
+
+// MarshalJSON encodes an Entry as a JSON object holding its color as a hex
+// string, as 8-bit RGB, as HSL and as CIELAB, along with its weight. The
+// color's alpha is given separately, in the range [0, 1], and translucent
+// colors have an eight digit hex string. Colors are encoded to 8-bit
+// precision. An Entry with a nil Color cannot be encoded.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	if e.Color == nil {
+		return nil, fmt.Errorf("cannot encode an entry with a nil color")
+	}
+	return json.Marshal(newEntryJSON(e, nil))
+}
+
+// UnmarshalJSON decodes an Entry encoded by MarshalJSON. Only the hex color
+// and the weight are decoded; the color is always a color.RGBA64.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var v entryJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c, err := parseHex(v.Hex)
+	if err != nil {
+		return err
+	}
+	*e = Entry{c, v.Weight}
+	return nil
+}
+
+// MarshalJSON encodes a Palette as a JSON object holding its entries, from the
+// most to the least dominant, each with the statistics of its cluster, along
+// with the palette's inertia, iterations, convergence and restarts. See
+// Entry.MarshalJSON, and OrderedPalette for encoding the entries in another
+// order.
+func (p *Palette) MarshalJSON() ([]byte, error) {
+	return OrderedPalette{Palette: p}.MarshalJSON()
+}
+
+// An OrderedPalette is a Palette along with the order in which to encode its
+// entries as JSON. If Order is empty, the entries are encoded from the most
+// to the least dominant, as by Palette.MarshalJSON.
+type OrderedPalette struct {
+	Palette *Palette
+	Order   []SortOrder
+}
+
+// MarshalJSON encodes an OrderedPalette in the same way as Palette.MarshalJSON,
+// with its entries sorted by Order as by Palette.Entries.
+func (o OrderedPalette) MarshalJSON() ([]byte, error) {
+	p := o.Palette
+	order := o.Order
+	if len(order) == 0 {
+		order = []SortOrder{ByWeightDescending}
+	}
+	v := paletteJSON{
+		Colors:     make([]entryJSON, 0, len(p.entries)),
+		Inertia:    p.inertia,
+		Iterations: p.iterations,
+		Converged:  p.converged,
+		Restarts:   p.restarts,
+	}
+	for _, entry := range p.Entries(order...) {
+		stats := p.Stats(entry.Color)
+		v.Colors = append(v.Colors, newEntryJSON(entry, &stats))
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a Palette encoded by MarshalJSON. Because colors are
+// encoded to 8-bit precision, colors which were not 8-bit colors to begin
+// with may not round-trip exactly.
+func (p *Palette) UnmarshalJSON(data []byte) error {
+	var v paletteJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	entries := make([]Entry, len(v.Colors))
+	stats := make([]ClusterStats, len(v.Colors))
+	for i, entry := range v.Colors {
+		c, err := parseHex(entry.Hex)
+		if err != nil {
+			return err
+		}
+		entries[i] = Entry{c, entry.Weight}
+		if entry.Stats != nil {
+			stats[i] = *entry.Stats
+		}
+	}
+	*p = *newPalette(entries, stats, v.Inertia, v.Iterations, v.Converged)
+	if v.Restarts > 0 {
+		p.restarts = v.Restarts
+	}
+	return nil
+}
+
+// newEntryJSON returns the JSON representation of an entry, with the given
+// stats, which may be nil.
+func newEntryJSON(e Entry, stats *ClusterStats) entryJSON {
+	c := color.NRGBAModel.Convert(e.Color).(color.NRGBA)
+
+	r, g, b := float64(c.R)/0xff, float64(c.G)/0xff, float64(c.B)/0xff
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	hsl := hslJSON{L: (hi + lo) / 2}
+	if hi != lo {
+		hsl.H = hue(color.NRGBA{c.R, c.G, c.B, 0xff})
+		hsl.S = (hi - lo) / (1 - math.Abs(2*hsl.L-1))
+	}
+
+	lab := rgbToLab(uint32(c.R)*0x101, uint32(c.G)*0x101, uint32(c.B)*0x101)
+	return entryJSON{
+		Hex:    hexString(e.Color),
+		RGB:    rgbJSON{c.R, c.G, c.B},
+		Alpha:  float64(c.A) / 0xff,
+		HSL:    hsl,
+		Lab:    labJSON{lab[0], lab[1], lab[2]},
+		Weight: e.Weight,
+		Stats:  stats,
+	}
+}
+
+// hexString formats a color as a "#rrggbb" hex string, or as "#rrggbbaa" if
+// it is translucent.
+func hexString(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A != 0xff {
+		return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
+
+// parseHex parses a color given as a "#rrggbb" or "#rrggbbaa" hex string.
+func parseHex(s string) (color.RGBA64, error) {
+	if (len(s) != 7 && len(s) != 9) || s[0] != '#' {
+		return color.RGBA64{}, fmt.Errorf("invalid hex color %q", s)
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA64{}, fmt.Errorf("invalid hex color %q", s)
+	}
+	if len(s) == 7 {
+		n = n<<8 | 0xff
+	}
+	return normalizeColor(color.NRGBA{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}), nil
+}
@@ -0,0 +1,177 @@
+package palettor
+
+import (
+	"encoding/json"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEntryJSON(t *testing.T) {
+	testCases := []struct {
+		entry    Entry
+		expected string
+	}{
+		{
+			Entry{color.RGBA{255, 0, 0, 255}, 0.5},
+			`{"hex":"#ff0000","rgb":{"r":255,"g":0,"b":0},"alpha":1,"hsl":{"h":0,"s":1,"l":0.5},"lab":{"l":53.`,
+		},
+		{
+			Entry{color.RGBA{255, 255, 255, 255}, 0.25},
+			`{"hex":"#ffffff","rgb":{"r":255,"g":255,"b":255},"alpha":1,"hsl":{"h":0,"s":0,"l":1},"lab":{"l":100`,
+		},
+		{
+			Entry{color.NRGBA{0, 0, 255, 0x80}, 0.25},
+			`{"hex":"#0000ff80","rgb":{"r":0,"g":0,"b":255},"alpha":0.50`,
+		},
+	}
+	for _, tc := range testCases {
+		data, err := json.Marshal(tc.entry)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !strings.HasPrefix(string(data), tc.expected) {
+			t.Errorf("expected JSON starting with %s, got %s", tc.expected, data)
+		}
+		if strings.Contains(string(data), "stats") {
+			t.Errorf("expected no stats in entry JSON, got %s", data)
+		}
+
+		var decoded Entry
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := Entry{normalizeColor(tc.entry.Color), tc.entry.Weight}
+		if decoded != expected {
+			t.Errorf("expected entry %v to round-trip, got %v", expected, decoded)
+		}
+	}
+}
+
+func TestEntryJSONInvalidHex(t *testing.T) {
+	for _, hex := range []string{"", "ff0000", "#ff00", "#ff00000", "#gg0000", "#+f0000"} {
+		var entry Entry
+		if err := json.Unmarshal([]byte(`{"hex":"`+hex+`","weight":1}`), &entry); err == nil {
+			t.Errorf("expected error for hex color %q", hex)
+		}
+	}
+}
+
+func TestEntryJSONNilColor(t *testing.T) {
+	if _, err := json.Marshal(Entry{nil, 1}); err == nil {
+		t.Errorf("expected error for nil color")
+	}
+}
+
+func TestPaletteJSON(t *testing.T) {
+	palette := newPalette([]Entry{
+		{color.RGBA{255, 0, 0, 255}, 0.25},
+		{color.RGBA{0, 0, 255, 255}, 0.75},
+	}, []ClusterStats{
+		{Count: 1, Variance: 2, MaxDistance: 3},
+		{Count: 3, Variance: 4, MaxDistance: 5},
+	}, 10, 7, true)
+	palette.restarts = 3
+
+	data, err := json.Marshal(palette)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Colors are listed from the most to the least dominant
+	var v struct {
+		Colors []struct {
+			Hex   string       `json:"hex"`
+			Stats ClusterStats `json:"stats"`
+		} `json:"colors"`
+		Iterations int  `json:"iterations"`
+		Converged  bool `json:"converged"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(v.Colors) != 2 || v.Colors[0].Hex != "#0000ff" || v.Colors[1].Hex != "#ff0000" {
+		t.Errorf("expected blue then red, got %s", data)
+	}
+	if v.Colors[0].Stats.Count != 3 || v.Iterations != 7 || !v.Converged {
+		t.Errorf("expected stats and metadata, got %s", data)
+	}
+
+	var decoded Palette
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(decoded.Entries(), palette.Entries()) {
+		t.Errorf("expected entries %v to round-trip, got %v", palette.Entries(), decoded.Entries())
+	}
+	for _, c := range palette.Colors() {
+		if decoded.Stats(c) != palette.Stats(c) {
+			t.Errorf("expected stats %v for %v, got %v", palette.Stats(c), c, decoded.Stats(c))
+		}
+	}
+	if decoded.Inertia() != 10 || decoded.Iterations() != 7 || !decoded.Converged() || decoded.Restarts() != 3 {
+		t.Errorf("expected metadata to round-trip, got %s", data)
+	}
+}
+
+func TestPaletteJSONOrder(t *testing.T) {
+	palette := newPalette([]Entry{
+		{color.RGBA{0, 0, 255, 255}, 0.25},
+		{color.RGBA{255, 0, 0, 255}, 0.5},
+		{color.RGBA{0, 255, 0, 255}, 0.25},
+	}, nil, 0, 1, true)
+
+	testCases := []struct {
+		palette  json.Marshaler
+		expected []string
+	}{
+		{palette, []string{"#ff0000", "#0000ff", "#00ff00"}},
+		{OrderedPalette{Palette: palette}, []string{"#ff0000", "#0000ff", "#00ff00"}},
+		{OrderedPalette{palette, []SortOrder{ByWeight}}, []string{"#0000ff", "#00ff00", "#ff0000"}},
+		{OrderedPalette{palette, []SortOrder{ByHue}}, []string{"#ff0000", "#00ff00", "#0000ff"}},
+		{OrderedPalette{palette, []SortOrder{ByLuminance}}, []string{"#0000ff", "#ff0000", "#00ff00"}},
+	}
+	for i, tc := range testCases {
+		data, err := json.Marshal(tc.palette)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var v struct {
+			Colors []struct {
+				Hex string `json:"hex"`
+			} `json:"colors"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		hexes := make([]string, len(v.Colors))
+		for j, c := range v.Colors {
+			hexes[j] = c.Hex
+		}
+		if !reflect.DeepEqual(hexes, tc.expected) {
+			t.Errorf("case %d: expected colors %v, got %v", i, tc.expected, hexes)
+		}
+	}
+
+	// The entries are the same in any order
+	data, err := json.Marshal(palette)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var decoded Palette
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err = json.Marshal(OrderedPalette{palette, []SortOrder{ByHue}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var decodedOrdered Palette
+	if err := json.Unmarshal(data, &decodedOrdered); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(decoded.Entries(), decodedOrdered.Entries()) {
+		t.Errorf("expected identical entries in any order, got %v and %v", decoded.Entries(), decodedOrdered.Entries())
+	}
+}
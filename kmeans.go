@@ -1,103 +1,523 @@
 package palettor
 
 import (
+	"context"
 	"fmt"
 	"image/color"
+	"math"
 	"math/rand"
-	"time"
+	"sync"
 )
 
-// clusterColors finds k clusters in the given colors using the "standard"
+// A centroid is the center of a cluster, given as its coordinates in the
+// color space used for clustering. If the centroid is one of the pixels being
+// clustered, index is that pixel's index; otherwise it is -1.
+type centroid struct {
+	point point
+	index int
+}
+
+// clusterColors finds opts.K clusters in the given colors using the "standard"
 // k-means clustering algorithm. It returns a Palette, after running the
-// algorithm up to maxIterations times.
+// algorithm up to opts.MaxIterations times, or an error if ctx is done before
+// then.
 //
 // Note: in terms of the standard algorithm[1], an observation in this
-// implementation is simply a color, and we use the RGB channels as Euclidean
-// coordinates for the purposes of finding the distance between two colors.
+// implementation is simply a color, and we use the distance between colors
+// given by opts.Metric. Each color is converted into the metric's color space
+// once, up front, and stored in a compact pixels buffer. Clusters are tracked
+// by recording the index of each pixel's centroid.
+//
+// Each color may carry a weight, as determined by opts.Alpha and
+// opts.HistogramBits, in which case centroids are weighted means and each
+// cluster's weight in the Palette is the sum of the weights of its colors.
 //
 // [1]: https://en.wikipedia.org/wiki/K-means_clustering#Standard_algorithm
-func clusterColors(k, maxIterations int, colors []color.Color) (*Palette, error) {
-	colorCount := len(colors)
-	if colorCount < k {
-		return nil, fmt.Errorf("too few colors for k (%d < %d)", colorCount, k)
+func clusterColors(ctx context.Context, colors []color.RGBA64, opts Options) (*Palette, error) {
+	colors, weights, counts, k, err := prepareColors(colors, opts)
+	if err != nil {
+		return nil, err
 	}
+	palette, _, err := cluster(ctx, newPixels(colors, weights, counts, opts.metric().Space()), k, opts)
+	return palette, err
+}
 
-	centroids := initializeStep(k, colors)
-	var clusters map[color.Color][]color.Color
-	var converged bool
+// prepareColors applies opts.Alpha and opts.HistogramBits to the given colors,
+// returning the colors to cluster, their weights (or nil weights if every
+// color has weight 1), the number of pixels each color stands for (or nil
+// counts if every color is a single pixel), and the number of clusters to
+// find, which is opts.K unless quantizing leaves fewer colors than that.
+func prepareColors(colors []color.RGBA64, opts Options) ([]color.RGBA64, []float32, []int32, int, error) {
+	k := opts.K
+	colors, weights := applyAlphaPolicy(colors, opts)
+	if len(colors) < k {
+		return nil, nil, nil, 0, fmt.Errorf("too few colors for k (%d < %d)", len(colors), k)
+	}
+
+	// Quantizing may leave fewer than k buckets, in which case the palette
+	// will have fewer than k colors.
+	var counts []int32
+	if opts.HistogramBits > 0 {
+		colors, weights, counts = quantize(colors, weights, opts.HistogramBits)
+		if len(colors) < k {
+			k = len(colors)
+		}
+	}
+	return colors, weights, counts, k, nil
+}
+
+// cluster finds k clusters in the given pixels, running k-means opts.Restarts
+// times with consecutive seeds and keeping the run with the lowest inertia.
+// It returns the kept run's Palette along with the final centroid of each of
+// its non-empty clusters. Ties go to the earliest run.
+func cluster(ctx context.Context, px *pixels, k int, opts Options) (*Palette, []centroid, error) {
+	seed := opts.seed()
+
+	type run struct {
+		palette   *Palette
+		centroids []centroid
+		err       error
+	}
+	runs := make([]run, opts.restarts())
+	do := func(i int) {
+		r := rand.New(rand.NewSource(seed + int64(i)))
+		runs[i].palette, runs[i].centroids, runs[i].err = clusterOnce(ctx, px, k, opts, r)
+	}
+	if opts.ConcurrentRestarts {
+		var wg sync.WaitGroup
+		for i := range runs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				do(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range runs {
+			do(i)
+			if runs[i].err != nil {
+				break
+			}
+		}
+	}
+
+	best := 0
+	for i, run := range runs {
+		if run.err != nil {
+			return nil, nil, run.err
+		}
+		if run.palette.Inertia() < runs[best].palette.Inertia() {
+			best = i
+		}
+	}
+	runs[best].palette.restarts = len(runs)
+	return runs[best].palette, runs[best].centroids, nil
+}
+
+// clusterOnce runs k-means once on the given pixels, using r to initialize
+// the centroids, returning the Palette along with the final centroid of each
+// non-empty cluster.
+func clusterOnce(ctx context.Context, px *pixels, k int, opts Options, r *rand.Rand) (*Palette, []centroid, error) {
+	metric := opts.metric()
+
+	// With mini-batches, the centroids are initialized from a sample of
+	// pixels too, since k-means++ initialization over every pixel would cost
+	// as much as several full iterations.
+	initPx := px
+	var sample []int
+	if n := 3 * opts.BatchSize; opts.BatchSize > 0 && n < px.count() && n >= k {
+		sample = sampleIndexes(n, px.count(), r)
+		initPx = px.subset(sample)
+	}
+	centroids := make([]centroid, 0, k)
+	for _, i := range initializeStep(k, initPx, metric, opts.Init, r) {
+		if sample != nil {
+			i = sample[i]
+		}
+		centroids = append(centroids, centroid{px.point(i), i})
+	}
+	assignments := make([]int32, px.count())
+	workers := opts.workers()
+	var sums []clusterSum
+
+	// step runs a full iteration of the standard algorithm, and reports
+	// whether it converged.
+	step := func() bool {
+		sums = assignmentStep(centroids, px, assignments, metric, workers)
+		reseeded := false
+		if opts.EmptyClusters != EmptyDrop && reseedEmptyClusters(centroids, sums, px, assignments, metric, opts.EmptyClusters) {
+			reseeded = true
+			sums = assignmentStep(centroids, px, assignments, metric, workers)
+		}
+		var converged bool
+		converged, centroids = updateStep(centroids, sums, px, assignments, metric, opts.Centroid, opts.Tolerance, workers)
+		return converged && !reseeded
+	}
 
 	// The algorithm isn't guaranteed to converge, so we put a limit on the
 	// number of attempts we will make.
 	var iterations int
-	for iterations = 0; iterations < maxIterations; iterations++ {
-		clusters = assignmentStep(centroids, colors)
-		converged, centroids = updateStep(clusters)
-		if converged {
-			break
+	var converged bool
+	if opts.BatchSize > 0 {
+		var err error
+		iterations, converged, err = miniBatch(ctx, centroids, px, metric, opts, r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// A final full iteration assigns every pixel to its cluster
+		step()
+	} else {
+		for iterations = 0; iterations < opts.MaxIterations; iterations++ {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			if converged = step(); converged {
+				break
+			}
 		}
 	}
 
-	clusterWeights := make(map[color.Color]float64, k)
-	for centroid, cluster := range clusters {
-		clusterWeights[centroid] = float64(len(cluster)) / float64(colorCount)
+	totalWeight := px.totalWeight()
+
+	// Mean centroids need the mean alpha of each cluster to be converted back
+	// to colors.
+	alphaSums := make([]float64, len(sums))
+	if opts.Centroid == CentroidMean {
+		for x, i := range assignments {
+			alphaSums[i] += px.weight(x) * float64(px.colors[x].A)
+		}
 	}
-	return &Palette{
-		colorWeights: clusterWeights,
-		iterations:   iterations,
-		converged:    converged,
-	}, nil
+
+	// Empty clusters have no centroid, so the remaining centroids line up
+	// with the non-empty clusters.
+	entries := make([]Entry, 0, len(centroids))
+	points := make([]point, len(sums))
+	i := 0
+	for j, s := range sums {
+		if s.weight == 0 {
+			continue
+		}
+		var c color.Color
+		if index := centroids[i].index; index >= 0 {
+			c = px.colors[index]
+		} else {
+			alpha := uint32(math.Round(alphaSums[j] / s.weight))
+			c = metric.Space().color(centroids[i].point, alpha)
+		}
+		entries = append(entries, Entry{c, s.weight / totalWeight})
+		points[j] = centroids[i].point
+		i++
+	}
+
+	stats, inertia := clusterStats(px, assignments, points, metric, workers)
+	nonEmpty := stats[:0]
+	for j, s := range sums {
+		if s.weight > 0 {
+			nonEmpty = append(nonEmpty, stats[j])
+		}
+	}
+	return newPalette(entries, nonEmpty, inertia, iterations, converged), centroids, nil
 }
 
-// Generate the initial list of k centroids from the given list of colors.
+// Pick the indexes of the initial k centroids from the given list of points,
+// using the given initialization method.
 //
-// TODO: Try other initialization methods?
 // https://en.wikipedia.org/wiki/K-means_clustering#Initialization_methods
-func initializeStep(k int, colors []color.Color) []color.Color {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	centroids := make([]color.Color, k)
-	colorCount := len(colors)
+func initializeStep(k int, px *pixels, metric Metric, method InitMethod, r *rand.Rand) []int {
+	if method == InitKMeansPlusPlus {
+		return initializePlusPlus(k, px, metric, r)
+	}
+	return initializeRandom(k, px.count(), r)
+}
+
+// Pick k distinct indexes of the given number of points uniformly at random.
+func initializeRandom(k int, pointCount int, r *rand.Rand) []int {
+	indexes := make([]int, k)
 
 	// Track random indexes we've used to avoid picking the same index for
-	// multiple centroids in the case len(colors) is close to k.
+	// multiple centroids in the case len(points) is close to k.
 	usedIndexes := make(map[int]struct{}, k)
 	var index int
 	for i := 0; i < k; i++ {
 		for {
-			index = r.Intn(colorCount)
+			index = r.Intn(pointCount)
 			if _, used := usedIndexes[index]; !used {
 				usedIndexes[index] = struct{}{}
 				break
 			}
 		}
-		centroids[i] = colors[index]
+		indexes[i] = index
+	}
+	return indexes
+}
+
+// Pick k points using k-means++ seeding: the first point is picked uniformly
+// at random, and each subsequent point is picked with probability
+// proportional to its weight times its squared distance from the nearest
+// point already picked.
+func initializePlusPlus(k int, px *pixels, metric Metric, r *rand.Rand) []int {
+	pointCount := px.count()
+	indexes := make([]int, 0, k)
+	indexes = append(indexes, r.Intn(pointCount))
+
+	// minDists[i] tracks the weighted squared distance from pixel i to its
+	// nearest centroid, updated incrementally as each new centroid is picked.
+	minDists := make([]float64, pointCount)
+	first := px.point(indexes[0])
+	for i := range minDists {
+		d := metric.Distance(first, px.point(i))
+		minDists[i] = px.weight(i) * d * d
+	}
+
+	for len(indexes) < k {
+		var total float64
+		for _, d := range minDists {
+			total += d
+		}
+
+		// Every point coincides with a centroid, so there are fewer distinct
+		// colors than k. Fall back to picking at random; the duplicate
+		// centroids will end up with empty clusters.
+		if total == 0 {
+			indexes = append(indexes, r.Intn(pointCount))
+			continue
+		}
+
+		target := r.Float64() * total
+		index := pointCount - 1
+		for i, d := range minDists {
+			target -= d
+			if target < 0 {
+				index = i
+				break
+			}
+		}
+		indexes = append(indexes, index)
+
+		next := px.point(index)
+		for i := range minDists {
+			d := metric.Distance(next, px.point(i))
+			if wd := px.weight(i) * d * d; wd < minDists[i] {
+				minDists[i] = wd
+			}
+		}
+	}
+	return indexes
+}
+
+// A clusterSum accumulates the weighted sum of the points in a cluster.
+type clusterSum struct {
+	sum    point
+	weight float64
+}
+
+// Assign each point to the cluster of the closest centroid, recording the
+// index of that centroid in assignments, and return the weighted sum of the
+// points in each cluster. The returned sums line up with the given centroids,
+// and a cluster with no points has zero weight.
+//
+// Points are processed in chunks spread across the given number of workers.
+func assignmentStep(centroids []centroid, px *pixels, assignments []int32, metric Metric, workers int) []clusterSum {
+	distance := ranking(metric)
+	partials := make([][]clusterSum, chunkCount(px.count()))
+	forEachChunk(px.count(), workers, func(chunk, start, end int) {
+		sums := make([]clusterSum, len(centroids))
+		for x := start; x < end; x++ {
+			p, w := px.point(x), px.weight(x)
+			i := nearest(p, centroids, distance)
+			assignments[x] = int32(i)
+			sums[i].sum[0] += w * p[0]
+			sums[i].sum[1] += w * p[1]
+			sums[i].sum[2] += w * p[2]
+			sums[i].weight += w
+		}
+		partials[chunk] = sums
+	})
+
+	sums := make([]clusterSum, len(centroids))
+	for _, partial := range partials {
+		for i, s := range partial {
+			sums[i].sum[0] += s.sum[0]
+			sums[i].sum[1] += s.sum[1]
+			sums[i].sum[2] += s.sum[2]
+			sums[i].weight += s.weight
+		}
+	}
+	return sums
+}
+
+// miniBatch moves the given centroids using mini-batch k-means[1], which
+// updates them from batches of opts.BatchSize pixels sampled at random using
+// r, rather than from every pixel. Each sampled pixel pulls its nearest
+// centroid towards it by a step which shrinks as the centroid accumulates
+// weight. It returns the number of batches used, and whether the centroids
+// converged, i.e. whether a batch moved none of them further than
+// opts.Tolerance.
+//
+// Each batch is assigned to the centroids in chunks spread across workers,
+// and the centroids are then updated in order, so the results do not depend
+// on the number of workers.
+//
+// [1]: Sculley, Web-Scale K-Means Clustering, 2010
+func miniBatch(ctx context.Context, centroids []centroid, px *pixels, metric Metric, opts Options, r *rand.Rand) (int, bool, error) {
+	batch := make([]int, opts.BatchSize)
+	nearestCentroids := make([]int, opts.BatchSize)
+	counts := make([]float64, len(centroids))
+	previous := make([]point, len(centroids))
+	workers := opts.workers()
+	distance := ranking(metric)
+
+	for iterations := 0; iterations < opts.MaxIterations; iterations++ {
+		if err := ctx.Err(); err != nil {
+			return iterations, false, err
+		}
+		for i := range batch {
+			batch[i] = r.Intn(px.count())
+		}
+		forEachChunk(len(batch), workers, func(chunk, start, end int) {
+			for i := start; i < end; i++ {
+				nearestCentroids[i] = nearest(px.point(batch[i]), centroids, distance)
+			}
+		})
+
+		for i, c := range centroids {
+			previous[i] = c.point
+		}
+		for i, x := range batch {
+			c, w := nearestCentroids[i], px.weight(x)
+			counts[c] += w
+			eta := w / counts[c]
+			p := px.point(x)
+			for axis := range p {
+				centroids[c].point[axis] += eta * (p[axis] - centroids[c].point[axis])
+			}
+			centroids[c].index = -1
+		}
+
+		converged := true
+		for i, c := range centroids {
+			if c.point != previous[i] && metric.Distance(previous[i], c.point) > opts.Tolerance {
+				converged = false
+				break
+			}
+		}
+		if converged {
+			return iterations, true, nil
+		}
+	}
+	return opts.MaxIterations, false, nil
+}
+
+// sampleIndexes picks n distinct indexes in the range [0, count) at random.
+func sampleIndexes(n, count int, r *rand.Rand) []int {
+	indexes := make([]int, 0, n)
+	seen := make(map[int]bool, n)
+	for len(indexes) < n {
+		if i := r.Intn(count); !seen[i] {
+			seen[i] = true
+			indexes = append(indexes, i)
+		}
 	}
-	return centroids
+	return indexes
 }
 
-// Assign each color to the cluster of the closest centroid.
-func assignmentStep(centroids, colors []color.Color) map[color.Color][]color.Color {
-	clusters := make(map[color.Color][]color.Color)
-	for _, x := range colors {
-		centroid := nearest(x, centroids)
-		cluster, found := clusters[centroid]
-		if !found {
-			// allocate slice w/ maximum possible capacity to avoid possible
-			// allocations per-append below
-			cluster = make([]color.Color, 0, len(colors))
+// Move the centroid of each empty cluster to a pixel which is not already a
+// centroid, as selected by the given policy, and report whether any centroids
+// were moved. The centroid of an empty cluster is left alone if every pixel
+// is already a centroid.
+//
+// Each pixel's distance to the nearest centroid is tracked as centroids are
+// moved, so that no two empty clusters are moved to the same color.
+func reseedEmptyClusters(centroids []centroid, sums []clusterSum, px *pixels, assignments []int32, metric Metric, policy EmptyClusterPolicy) bool {
+	var empty []int
+	weights := make([]float64, len(sums))
+	for i, s := range sums {
+		if s.weight == 0 {
+			empty = append(empty, i)
+		}
+		weights[i] = s.weight
+	}
+	if len(empty) == 0 {
+		return false
+	}
+
+	dists := make([]float64, px.count())
+	for x := range dists {
+		dists[x] = metric.Distance(centroids[assignments[x]].point, px.point(x))
+	}
+	farthest := func(cluster int) int {
+		result := -1
+		for x, d := range dists {
+			if d > 0 && (cluster < 0 || int(assignments[x]) == cluster) && (result < 0 || d > dists[result]) {
+				result = x
+			}
 		}
-		clusters[centroid] = append(cluster, x)
+		return result
 	}
-	return clusters
+
+	reseeded := false
+	for _, i := range empty {
+		x := -1
+		if policy == EmptySplitLargest {
+			heaviest := 0
+			for j, w := range weights {
+				if w > weights[heaviest] {
+					heaviest = j
+				}
+			}
+			x = farthest(heaviest)
+		}
+		// Fall back to the farthest pixel overall if the heaviest cluster
+		// is a single color.
+		if x < 0 {
+			x = farthest(-1)
+		}
+		if x < 0 {
+			break
+		}
+
+		centroids[i] = centroid{px.point(x), x}
+		reseeded = true
+		weights[assignments[x]] -= px.weight(x)
+		weights[i] += px.weight(x)
+		assignments[x] = int32(i)
+		for y := range dists {
+			if d := metric.Distance(centroids[i].point, px.point(y)); d < dists[y] {
+				dists[y] = d
+			}
+		}
+	}
+	return reseeded
 }
 
-// Pick new centroids from each cluster. If none of the centroids change, the
-// clusters have stabilized and the algorithm has converged.
-func updateStep(clusters map[color.Color][]color.Color) (bool, []color.Color) {
+// Compute new centroids for each non-empty cluster, in order. If none of the
+// centroids move by more than the given tolerance, measured by the metric,
+// the clusters have stabilized and the algorithm has converged.
+func updateStep(centroids []centroid, sums []clusterSum, px *pixels, assignments []int32, metric Metric, method CentroidMethod, tolerance float64, workers int) (bool, []centroid) {
+	means := make([]point, len(sums))
+	for i, s := range sums {
+		if s.weight > 0 {
+			means[i] = point{s.sum[0] / s.weight, s.sum[1] / s.weight, s.sum[2] / s.weight}
+		}
+	}
+	var medoids []int
+	if method == CentroidMedoid {
+		medoids = findMedoids(means, px, assignments, metric, workers)
+	}
+
 	converged := true
-	newCentroids := make([]color.Color, 0, len(clusters))
-	for centroid, cluster := range clusters {
-		newCentroid := findCentroid(cluster)
-		if newCentroid != centroid {
+	newCentroids := make([]centroid, 0, len(sums))
+	for i, s := range sums {
+		if s.weight == 0 {
+			continue
+		}
+		newCentroid := centroid{means[i], -1}
+		if medoids != nil {
+			newCentroid = centroid{px.point(medoids[i]), medoids[i]}
+		}
+		if newCentroid.point != centroids[i].point && metric.Distance(centroids[i].point, newCentroid.point) > tolerance {
 			converged = false
 		}
 		newCentroids = append(newCentroids, newCentroid)
@@ -105,56 +525,73 @@ func updateStep(clusters map[color.Color][]color.Color) (bool, []color.Color) {
 	return converged, newCentroids
 }
 
-// Find the color closest to the mean of the given colors.
+// Find the index of the point in each cluster closest to the cluster's mean,
+// or -1 for empty clusters. Ties go to the earliest point.
 //
 // Note: I think this is a departure from the "standard" algorithm, which seems
-// to instead use the actual mean of the given colors (which is likely
-// not actually present in those colors).
-func findCentroid(colors []color.Color) color.Color {
-	center := meanColor(colors)
-	return nearest(center, colors)
-}
-
-// Find the average color in a list of colors.
-func meanColor(colors []color.Color) color.Color {
-	var r, g, b, a uint32
-	for _, x := range colors {
-		r1, g1, b1, a1 := x.RGBA()
-		r += r1
-		g += g1
-		b += b1
-		a += a1
-	}
-	count := uint32(len(colors))
-	return &color.RGBA64{
-		R: uint16(r / count),
-		G: uint16(g / count),
-		B: uint16(b / count),
-		A: uint16(a / count),
-	}
-}
-
-// Find the item in the haystack to which the needle is closest.
-func nearest(needle color.Color, haystack []color.Color) color.Color {
-	var minDist int
-	var result color.Color
-	for i, candidate := range haystack {
-		dist := distanceSquared(needle, candidate)
+// to instead use the actual mean of the given points (which is likely
+// not actually present in those points).
+func findMedoids(means []point, px *pixels, assignments []int32, metric Metric, workers int) []int {
+	type candidate struct {
+		index int
+		dist  float64
+	}
+	newCandidates := func() []candidate {
+		candidates := make([]candidate, len(means))
+		for i := range candidates {
+			candidates[i].index = -1
+		}
+		return candidates
+	}
+
+	partials := make([][]candidate, chunkCount(px.count()))
+	forEachChunk(px.count(), workers, func(chunk, start, end int) {
+		best := newCandidates()
+		for x := start; x < end; x++ {
+			i := assignments[x]
+			dist := metric.Distance(means[i], px.point(x))
+			if best[i].index < 0 || dist < best[i].dist {
+				best[i] = candidate{x, dist}
+			}
+		}
+		partials[chunk] = best
+	})
+
+	best := newCandidates()
+	for _, partial := range partials {
+		for i, c := range partial {
+			if c.index >= 0 && (best[i].index < 0 || c.dist < best[i].dist) {
+				best[i] = c
+			}
+		}
+	}
+	medoids := make([]int, len(best))
+	for i, c := range best {
+		medoids[i] = c.index
+	}
+	return medoids
+}
+
+// Find the index of the centroid to which the point is closest, as measured
+// by the given distance, which is usually the ranking of a Metric. Ties go to
+// the earliest centroid.
+func nearest(needle point, centroids []centroid, distance DistanceFunc) int {
+	var minDist float64
+	var result int
+	for i, candidate := range centroids {
+		dist := distance(candidate.point, needle)
 		if i == 0 || dist < minDist {
 			minDist = dist
-			result = candidate
+			result = i
 		}
 	}
 	return result
 }
 
-// Calculate the square of the Euclidean distance between two colors, ignoring
-// the alpha channel.
-func distanceSquared(a, b color.Color) int {
-	r1, g1, b1, _ := a.RGBA()
-	r2, g2, b2, _ := b.RGBA()
-	dr := int(r1) - int(r2)
-	dg := int(g1) - int(g2)
-	db := int(b1) - int(b2)
-	return dr*dr + dg*dg + db*db
+// Calculate the square of the Euclidean distance between two points.
+func distanceSquared(a, b point) float64 {
+	d0 := a[0] - b[0]
+	d1 := a[1] - b[1]
+	d2 := a[2] - b[2]
+	return d0*d0 + d1*d1 + d2*d2
 }
@@ -1,11 +1,15 @@
 package palettor
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/color"
 	_ "image/jpeg"
+	"math"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -19,6 +23,13 @@ var (
 	blue      = newColor(0, 0, 255, 0)
 	darkGray  = newColor(1, 1, 1, 0)
 	mostlyRed = newColor(200, 0, 0, 0)
+
+	// Opaque versions of the colors above, for tests in which alpha matters
+	opaqueBlack = color.RGBA{0, 0, 0, 255}
+	opaqueWhite = color.RGBA{255, 255, 255, 255}
+	opaqueRed   = color.RGBA{255, 0, 0, 255}
+	opaqueGreen = color.RGBA{0, 255, 0, 255}
+	opaqueBlue  = color.RGBA{0, 0, 255, 255}
 )
 
 func randomColor() color.Color {
@@ -35,64 +46,74 @@ func newColor(r, g, b, a int) color.Color {
 }
 
 func TestDistanceSquared(t *testing.T) {
-	a := newColor(0, 0, 0, 0)
-	b := newColor(255, 255, 255, 0)
-	expected := (0xFFFF * 0xFFFF) + (0xFFFF * 0xFFFF) + (0xFFFF * 0xFFFF)
+	a := testPoint(RGB, newColor(0, 0, 0, 0))
+	b := testPoint(RGB, newColor(255, 255, 255, 0))
+	const expected = (0xFFFF * 0xFFFF) + (0xFFFF * 0xFFFF) + (0xFFFF * 0xFFFF)
 	if distanceSquared(a, b) != expected {
-		t.Errorf("distance should be square of Euclidean distance; %d != %d", distanceSquared(a, b), expected)
+		t.Errorf("distance should be square of Euclidean distance; %v != %d", distanceSquared(a, b), expected)
 	}
 
-	a = newColor(0, 0, 0, 0)
-	b = newColor(0, 0, 0, 255)
+	a = testPoint(RGB, newColor(0, 0, 0, 0))
+	b = testPoint(RGB, newColor(0, 0, 0, 255))
 	if distanceSquared(a, b) != 0 {
 		t.Errorf("alpha channel is ignored for the purpose of distance")
 	}
 
-	c := randomColor()
+	c := testPoint(RGB, randomColor())
 	if distanceSquared(c, c) != 0 {
 		t.Errorf("distance from between identical colors should be 0")
 	}
 }
 
 func TestNearest(t *testing.T) {
-	var haystack = []color.Color{black, white, red, green, blue}
+	colors := []color.Color{black, white, red, green, blue}
+	var haystack []centroid
+	for i, c := range colors {
+		haystack = append(haystack, centroid{testPoint(RGB, c), i})
+	}
 
-	if nearest(black, haystack) != black {
+	if colors[nearest(testPoint(RGB, black), haystack, ranking(EuclideanRGB))] != black {
 		t.Errorf("nearest color to self should be self")
 	}
-	if nearest(darkGray, haystack) != black {
+	if colors[nearest(testPoint(RGB, darkGray), haystack, ranking(EuclideanRGB))] != black {
 		t.Errorf("dark gray should be nearest to black")
 	}
-	if nearest(mostlyRed, haystack) != red {
+	if colors[nearest(testPoint(RGB, mostlyRed), haystack, ranking(EuclideanRGB))] != red {
 		t.Errorf("mostly-red should be nearest to red")
 	}
 }
 
-func TestFindCentroid(t *testing.T) {
-	var cluster = []color.Color{black, white, red, mostlyRed}
-	centroid := findCentroid(cluster)
-	found := false
-	for _, c := range cluster {
-		if c == centroid {
-			found = true
-		}
+func TestFindMedoids(t *testing.T) {
+	px := testPixels(black, white, red, mostlyRed, blue)
+	assignments := []int32{0, 0, 0, 0, 1}
+	means := []point{
+		{0xffff / 2, 0xffff / 4, 0xffff / 4},
+		px.point(4),
+		{},
 	}
-	if !found {
+	medoids := findMedoids(means, px, assignments, EuclideanRGB, 1)
+	if medoids[0] < 0 || medoids[0] > 3 {
 		t.Errorf("centroid should be a member of the cluster")
 	}
+	if medoids[1] != 4 {
+		t.Errorf("centroid of single-color cluster should be that color")
+	}
+	if medoids[2] != -1 {
+		t.Errorf("empty cluster should have no centroid")
+	}
 }
 
 func TestCluster(t *testing.T) {
-	var colors = []color.Color{black, white, red}
+	var colors = rgba64s(black, white, red)
 
 	k := 4
-	_, err := clusterColors(k, 100, colors)
+	_, err := clusterColors(context.Background(), colors, Options{K: k, MaxIterations: 100})
 	if err == nil {
 		t.Errorf("too few colors should result in an error")
 	}
 
 	k = 3
-	palette, err := clusterColors(k, 100, colors)
+	palette, err := clusterColors(context.Background(), colors, Options{K: k, MaxIterations: 100})
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
@@ -101,8 +122,8 @@ func TestCluster(t *testing.T) {
 	}
 
 	k = 2
-	colors = []color.Color{black, white}
-	palette, _ = clusterColors(k, 100, colors)
+	colors = rgba64s(black, white)
+	palette, _ = clusterColors(context.Background(), colors, Options{K: k, MaxIterations: 100})
 	if palette.Weight(black) != 0.5 {
 		t.Errorf("expected weight of black cluster to be 0.5")
 	}
@@ -113,30 +134,466 @@ func TestCluster(t *testing.T) {
 	// If there are not enough unique colors to cluster, it's okay for the size
 	// of the extracted palette to be < k
 	k = 3
-	palette, _ = clusterColors(k, 100, []color.Color{black, black, black, black, black, white})
+	palette, _ = clusterColors(context.Background(), rgba64s(black, black, black, black, black, white), Options{K: k, MaxIterations: 100})
 	if palette.Count() > 2 {
 		t.Errorf("actual palette can be smaller than k")
 	}
 }
 
-func BenchmarkClusterColors200x200(b *testing.B) {
-	reader, err := os.Open("testdata/resized.jpg")
+func TestClusterMean(t *testing.T) {
+	colors := rgba64s(opaqueBlack, opaqueWhite)
+
+	// The mean of black and white is gray, which is not one of the colors
+	palette, err := clusterColors(context.Background(), colors, Options{K: 1, MaxIterations: 100, Centroid: CentroidMean})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	gray := color.RGBA64{0x8000, 0x8000, 0x8000, 0xffff}
+	if palette.Weight(gray) != 1 {
+		t.Errorf("expected mean centroid %v, got %v", gray, palette.Entries())
+	}
+
+	palette, err = clusterColors(context.Background(), colors, Options{K: 1, MaxIterations: 100, Centroid: CentroidMedoid})
 	if err != nil {
-		b.Fatal(err)
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Weight(opaqueBlack) != 1 && palette.Weight(opaqueWhite) != 1 {
+		t.Errorf("expected medoid centroid to be one of the colors, got %v", palette.Entries())
+	}
+
+	colors = getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	for _, space := range []ColorSpace{RGB, Lab} {
+		opts := Options{K: 4, MaxIterations: 100, Seed: 1, ColorSpace: space, Centroid: CentroidMean}
+		mean, err := clusterColors(context.Background(), colors, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !mean.Converged() {
+			t.Errorf("expected mean centroids to converge")
+		}
+		if mean.Count() != 4 {
+			t.Errorf("expected 4 colors, got %d", mean.Count())
+		}
+	}
+}
+
+func TestClusterSeed(t *testing.T) {
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	opts := Options{K: 4, MaxIterations: 100, Seed: 42}
+
+	first, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := 0; i < 5; i++ {
+		palette, err := clusterColors(context.Background(), colors, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(palette.Entries(), first.Entries()) {
+			t.Fatalf("expected identical palettes for identical seeds, got %v and %v", first.Entries(), palette.Entries())
+		}
+		if palette.Iterations() != first.Iterations() {
+			t.Fatalf("expected identical iterations for identical seeds, got %d and %d", first.Iterations(), palette.Iterations())
+		}
+	}
+}
+
+func TestInitializePlusPlus(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	px := testPixels(black, white, red, green, blue)
+	indexes := initializePlusPlus(px.count(), px, EuclideanRGB, r)
+	picked := make(map[int]bool)
+	for _, i := range indexes {
+		picked[i] = true
+	}
+	if len(picked) != px.count() {
+		t.Errorf("expected every distinct color to be picked as a centroid, got %v", indexes)
+	}
+
+	// Too few distinct colors should still result in k centroids
+	px = testPixels(black, black, black, white)
+	if centroids := initializePlusPlus(3, px, EuclideanRGB, r); len(centroids) != 3 {
+		t.Errorf("expected 3 centroids, got %d", len(centroids))
+	}
+}
+
+// k-means++ initialization should need fewer iterations to converge than
+// random initialization, on average.
+func TestKMeansPlusPlusIterations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+
+	totalIterations := func(init InitMethod) int {
+		total := 0
+		for seed := int64(1); seed <= 10; seed++ {
+			palette, err := clusterColors(context.Background(), colors, Options{K: 8, MaxIterations: 100, Seed: seed, Init: init})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			total += palette.Iterations()
+		}
+		return total
+	}
+
+	random := totalIterations(InitRandom)
+	plusPlus := totalIterations(InitKMeansPlusPlus)
+	if plusPlus >= random {
+		t.Errorf("expected k-means++ to need fewer iterations than random init, got %d >= %d", plusPlus, random)
+	}
+}
+
+func TestClusterWorkers(t *testing.T) {
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	for _, centroid := range []CentroidMethod{CentroidMedoid, CentroidMean} {
+		opts := Options{K: 5, MaxIterations: 100, Seed: 7, Centroid: centroid, Workers: 1}
+		expected, err := clusterColors(context.Background(), colors, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, workers := range []int{2, 3, 8} {
+			opts.Workers = workers
+			palette, err := clusterColors(context.Background(), colors, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(palette.Entries(), expected.Entries()) {
+				t.Errorf("expected identical palettes for %d workers, got %v and %v", workers, expected.Entries(), palette.Entries())
+			}
+		}
+	}
+}
+
+// rgba64s converts the given colors to color.RGBA64 values.
+func rgba64s(colors ...color.Color) []color.RGBA64 {
+	result := make([]color.RGBA64, len(colors))
+	for i, c := range colors {
+		result[i] = toRGBA64(c)
+	}
+	return result
+}
+
+// testPixels returns unweighted RGB pixels with the given colors.
+func testPixels(colors ...color.Color) *pixels {
+	return newPixels(rgba64s(colors...), nil, nil, RGB)
+}
+
+// testPoint returns the coordinates of a color in the given color space, as
+// stored in a pixels buffer.
+func testPoint(space ColorSpace, c color.Color) point {
+	return newPixels(rgba64s(c), nil, nil, space).point(0)
+}
+
+func loadTestImage(tb testing.TB, path string) image.Image {
+	reader, err := os.Open(path)
+	if err != nil {
+		tb.Fatal(err)
 	}
 	defer reader.Close()
 
 	img, _, err := image.Decode(reader)
 	if err != nil {
-		b.Fatal(err)
+		tb.Fatal(err)
 	}
+	return img
+}
 
-	colors := getColors(img)
+func BenchmarkClusterColors200x200(b *testing.B) {
+	colors := getPixels(loadTestImage(b, "testdata/resized.jpg"))
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := clusterColors(4, 100, colors); err != nil {
+		if _, err := clusterColors(context.Background(), colors, Options{K: 4, MaxIterations: 100}); err != nil {
 			b.Error(err)
 		}
 	}
 }
+
+func BenchmarkClusterColorsWorkers(b *testing.B) {
+	colors := getPixels(loadTestImage(b, "testdata/original.jpg"))
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := Options{K: 4, MaxIterations: 10, Seed: 1, Workers: workers}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := clusterColors(context.Background(), colors, opts); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestClusterRestarts(t *testing.T) {
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+
+	// The kept palette is the one with the lowest inertia across the seeds
+	// tried by each restart
+	var inertias []float64
+	for seed := int64(1); seed <= 5; seed++ {
+		palette, err := clusterColors(context.Background(), colors, Options{K: 6, MaxIterations: 100, Seed: seed, Restarts: 1, HistogramBits: DefaultHistogramBits})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if palette.Restarts() != 1 {
+			t.Errorf("expected 1 restart, got %d", palette.Restarts())
+		}
+		inertias = append(inertias, palette.Inertia())
+	}
+	lowest := inertias[0]
+	for _, inertia := range inertias {
+		lowest = math.Min(lowest, inertia)
+	}
+
+	opts := Options{K: 6, MaxIterations: 100, Seed: 1, Restarts: 5, HistogramBits: DefaultHistogramBits}
+	expected, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected.Restarts() != 5 {
+		t.Errorf("expected 5 restarts, got %d", expected.Restarts())
+	}
+	if expected.Inertia() != lowest {
+		t.Errorf("expected lowest inertia %v of %v, got %v", lowest, inertias, expected.Inertia())
+	}
+
+	opts.ConcurrentRestarts = true
+	palette, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(palette.Entries(), expected.Entries()) {
+		t.Errorf("expected identical palettes w/ concurrent restarts, got %v and %v", expected.Entries(), palette.Entries())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := clusterColors(ctx, colors, opts); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestClusterTolerance(t *testing.T) {
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+
+	opts := Options{K: 6, MaxIterations: 100, Seed: 3, Metric: CIE76, Centroid: CentroidMean}
+	exact, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Centroids which move by less than a just-noticeable difference count
+	// as converged, which takes fewer iterations
+	opts.Tolerance = 1
+	tolerant, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !tolerant.Converged() {
+		t.Errorf("expected clusters to converge within tolerance")
+	}
+	if tolerant.Iterations() >= exact.Iterations() {
+		t.Errorf("expected fewer iterations w/ tolerance, got %d >= %d", tolerant.Iterations(), exact.Iterations())
+	}
+
+	// With a huge tolerance, the first iteration counts as converged
+	opts.Tolerance = 1000
+	palette, err := clusterColors(context.Background(), colors, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !palette.Converged() || palette.Iterations() != 0 {
+		t.Errorf("expected convergence after the first iteration, got %d iterations", palette.Iterations())
+	}
+}
+
+func TestClusterEmptyClusters(t *testing.T) {
+	// With mostly black pixels, random initialization often picks black for
+	// several centroids, leaving all but one of them with empty clusters.
+	var colors []color.RGBA64
+	for i := 0; i < 97; i++ {
+		colors = append(colors, toRGBA64(opaqueBlack))
+	}
+	colors = append(colors, rgba64s(opaqueWhite, opaqueRed, opaqueBlue)...)
+
+	dropped := 0
+	for seed := int64(1); seed <= 20; seed++ {
+		for _, policy := range []EmptyClusterPolicy{EmptyDrop, EmptyReseedFarthest, EmptySplitLargest} {
+			for _, centroid := range []CentroidMethod{CentroidMedoid, CentroidMean} {
+				opts := Options{K: 4, MaxIterations: 100, Seed: seed, Centroid: centroid, EmptyClusters: policy}
+				palette, err := clusterColors(context.Background(), colors, opts)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if policy == EmptyDrop {
+					if palette.Count() < 4 {
+						dropped++
+					}
+					continue
+				}
+				if palette.Count() != 4 {
+					t.Errorf("policy %d, seed %d: expected 4 colors, got %v", policy, seed, palette.Entries())
+				}
+				if !palette.Converged() {
+					t.Errorf("policy %d, seed %d: expected clusters to converge", policy, seed)
+				}
+			}
+		}
+	}
+	if dropped == 0 {
+		t.Errorf("expected some palettes to drop empty clusters")
+	}
+
+	// With fewer distinct colors than k, there is nowhere to reseed
+	colors = rgba64s(opaqueBlack, opaqueBlack, opaqueBlack, opaqueWhite)
+	for _, policy := range []EmptyClusterPolicy{EmptyReseedFarthest, EmptySplitLargest} {
+		palette, err := clusterColors(context.Background(), colors, Options{K: 3, MaxIterations: 100, Seed: 1, EmptyClusters: policy})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if palette.Count() != 2 {
+			t.Errorf("policy %d: expected 2 colors, got %v", policy, palette.Entries())
+		}
+	}
+}
+
+func TestReseedEmptyClusters(t *testing.T) {
+	px := testPixels(black, darkGray, blue, red, mostlyRed, black)
+	centroids := []centroid{{px.point(0), 0}, {px.point(3), 3}, {px.point(5), 5}}
+	assignments := make([]int32, px.count())
+	sums := assignmentStep(centroids, px, assignments, EuclideanRGB, 1)
+	if sums[2].weight != 0 {
+		t.Fatalf("expected duplicate centroid to have an empty cluster")
+	}
+
+	// Blue is furthest from its centroid, black, overall; mostly-red is
+	// furthest from its centroid in the heaviest cluster, red.
+	testCases := []struct {
+		policy   EmptyClusterPolicy
+		expected int
+	}{
+		{EmptyReseedFarthest, 2},
+		{EmptySplitLargest, 4},
+	}
+	for _, tc := range testCases {
+		// Make red's cluster the heaviest
+		px.weights = []float32{1, 1, 1, 5, 1, 1}
+		reseeded := make([]centroid, len(centroids))
+		copy(reseeded, centroids)
+		assignments := make([]int32, px.count())
+		sums := assignmentStep(reseeded, px, assignments, EuclideanRGB, 1)
+		if !reseedEmptyClusters(reseeded, sums, px, assignments, EuclideanRGB, tc.policy) {
+			t.Fatalf("policy %d: expected empty cluster to be reseeded", tc.policy)
+		}
+		if reseeded[2].index != tc.expected {
+			t.Errorf("policy %d: expected reseed at pixel %d, got %d", tc.policy, tc.expected, reseeded[2].index)
+		}
+	}
+}
+
+func TestClusterMiniBatch(t *testing.T) {
+	colors := []color.Color{
+		color.RGBA{220, 20, 20, 255},
+		color.RGBA{20, 200, 20, 255},
+		color.RGBA{20, 20, 220, 255},
+		color.RGBA{240, 240, 240, 255},
+	}
+	pixels := getPixels(blocksImage(colors, []int{400, 300, 200, 100}))
+
+	// The final full iteration gives exact weights and medoids
+	opts := Options{K: 4, MaxIterations: 20, Seed: 1, Init: InitKMeansPlusPlus, BatchSize: 256}
+	palette, err := clusterColors(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, expected := range []float64{0.4, 0.3, 0.2, 0.1} {
+		if w := palette.Weight(colors[i]); w != expected {
+			t.Errorf("expected weight %v for %v, got %v", expected, colors[i], w)
+		}
+	}
+
+	// Mini-batch k-means is reproducible with a seed, and comes close to
+	// the full-batch result on a real image. Either can land in a poor local
+	// minimum, so both keep the best of a few restarts.
+	pixels = getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	opts = Options{K: 4, MaxIterations: 100, Seed: 1, Init: InitKMeansPlusPlus, Centroid: CentroidMean, Restarts: 3}
+	full, err := clusterColors(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	opts.BatchSize = 1024
+	opts.Tolerance = 100
+	first, err := clusterColors(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	second, err := clusterColors(context.Background(), pixels, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(first.Entries(), second.Entries()) {
+		t.Errorf("expected identical palettes for identical seeds, got %v and %v", first.Entries(), second.Entries())
+	}
+	if !first.Converged() || first.Iterations() >= opts.MaxIterations {
+		t.Errorf("expected mini-batch k-means to converge within tolerance, got %d iterations", first.Iterations())
+	}
+	if first.Inertia() > 1.1*full.Inertia() {
+		t.Errorf("expected inertia within 10%% of full-batch inertia %v, got %v", full.Inertia(), first.Inertia())
+	}
+}
+
+// syntheticImage returns a large image made of noisy bands of a handful of
+// colors, seeded for reproducibility.
+func syntheticImage(width, height int) image.Image {
+	r := rand.New(rand.NewSource(1))
+	bands := []color.RGBA{
+		{200, 40, 40, 255},
+		{40, 160, 60, 255},
+		{30, 50, 180, 255},
+		{230, 220, 200, 255},
+		{90, 70, 50, 255},
+		{20, 20, 30, 255},
+	}
+	noise := func(x uint8) uint8 {
+		v := int(x) + r.Intn(41) - 20
+		if v < 0 {
+			return 0
+		} else if v > 255 {
+			return 255
+		}
+		return uint8(v)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := bands[(x/97+y/61)%len(bands)]
+			img.SetRGBA(x, y, color.RGBA{noise(c.R), noise(c.G), noise(c.B), 255})
+		}
+	}
+	return img
+}
+
+func BenchmarkClusterColorsMiniBatch(b *testing.B) {
+	colors := getPixels(syntheticImage(3000, 2000))
+	for _, batchSize := range []int{0, 1024, 4096} {
+		name := fmt.Sprintf("batch=%d", batchSize)
+		if batchSize == 0 {
+			name = "full"
+		}
+		b.Run(name, func(b *testing.B) {
+			opts := Options{K: 6, MaxIterations: 100, Seed: 1, Init: InitKMeansPlusPlus, Centroid: CentroidMean, BatchSize: batchSize, Tolerance: 100}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				palette, err := clusterColors(context.Background(), colors, opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(palette.Inertia()/float64(len(colors)), "inertia/px")
+			}
+		})
+	}
+}
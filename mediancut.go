@@ -0,0 +1,167 @@
+package palettor
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// ExtractMedianCut finds up to opts.K dominant colors in the given image using
+// the median cut algorithm[1], as an alternative to k-means clustering. Each
+// color in the Palette is the weighted mean of one box of colors, and its
+// weight is the share of the image's pixels which fall into that box, as with
+// ExtractWithOptions.
+//
+// Boxes are split in the color space of opts.Metric (or opts.ColorSpace), and
+// opts.Alpha, opts.HistogramBits and the sampling options are honored. Median
+// cut is deterministic and does not iterate, so opts.MaxIterations, opts.Init,
+// opts.Centroid and opts.Restarts are ignored, and the Palette always reports
+// zero iterations and convergence.
+//
+// ExtractMedianCut is shorthand for the Extract method of MedianCut, with a
+// background context.
+//
+// [1]: https://en.wikipedia.org/wiki/Median_cut
+func ExtractMedianCut(img image.Image, opts Options) (*Palette, error) {
+	return MedianCut{opts}.Extract(context.Background(), img)
+}
+
+// A box is a set of pixels which median cut may split in two.
+type box struct {
+	// indexes holds the indexes of the pixels in the box.
+	indexes []int32
+
+	// weight is the total weight of the pixels in the box.
+	weight float64
+
+	// axis is the color space axis along which the pixels' coordinates vary
+	// the most, and min and spread are the lowest of their coordinates along
+	// it and the range of their coordinates along it.
+	axis   int
+	min    float64
+	spread float64
+}
+
+// splitBins is the number of bins into which a box's widest axis is divided
+// to find its weighted median.
+const splitBins = 256
+
+// medianCut finds up to opts.K boxes of colors by repeatedly splitting the box
+// with the greatest spread, scaled by its weight, at the weighted median of
+// its widest axis. Boxes of identical colors are never split, so the palette
+// may have fewer than opts.K colors.
+func medianCut(ctx context.Context, colors []color.RGBA64, opts Options) (*Palette, error) {
+	colors, weights, counts, k, err := prepareColors(colors, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	metric := opts.metric()
+	px := newPixels(colors, weights, counts, metric.Space())
+	indexes := make([]int32, px.count())
+	for i := range indexes {
+		indexes[i] = int32(i)
+	}
+	boxes := []box{newBox(px, indexes)}
+
+	for len(boxes) < k {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		widest := -1
+		for i, b := range boxes {
+			if b.spread > 0 && (widest < 0 || b.spread*b.weight > boxes[widest].spread*boxes[widest].weight) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		lower, upper := splitBox(px, boxes[widest])
+		boxes[widest] = lower
+		boxes = append(boxes, upper)
+	}
+
+	totalWeight := px.totalWeight()
+	entries := make([]Entry, len(boxes))
+	points := make([]point, len(boxes))
+	labels := make([]int32, px.count())
+	for i, b := range boxes {
+		var sum bucket
+		for _, x := range b.indexes {
+			sum.add(colors[x], px.weight(int(x)))
+			labels[x] = int32(i)
+		}
+		c := sum.color()
+		entries[i] = Entry{c, b.weight / totalWeight}
+		points[i] = metric.Space().rgbPoint(uint32(c.R), uint32(c.G), uint32(c.B))
+	}
+
+	stats, inertia := clusterStats(px, labels, points, metric, opts.workers())
+	return newPalette(entries, stats, inertia, 0, true), nil
+}
+
+// newBox returns a box holding the pixels with the given indexes.
+func newBox(px *pixels, indexes []int32) box {
+	b := box{indexes: indexes}
+	var lo, hi point
+	for n, i := range indexes {
+		p := px.point(int(i))
+		for axis := range p {
+			if n == 0 || p[axis] < lo[axis] {
+				lo[axis] = p[axis]
+			}
+			if n == 0 || p[axis] > hi[axis] {
+				hi[axis] = p[axis]
+			}
+		}
+		b.weight += px.weight(int(i))
+	}
+	for axis := range lo {
+		if spread := hi[axis] - lo[axis]; spread > b.spread {
+			b.axis = axis
+			b.min = lo[axis]
+			b.spread = spread
+		}
+	}
+	return b
+}
+
+// splitBox splits a box with a non-zero spread at the weighted median of its
+// widest axis, such that both halves are non-empty. The median is found to
+// within 1/splitBins of the spread, using a histogram of the pixels'
+// coordinates along the axis, and pixels with equal coordinates always end up
+// in the same half.
+func splitBox(px *pixels, b box) (box, box) {
+	bin := func(i int32) int {
+		x := int((float64(px.coords[3*int(i)+b.axis]) - b.min) / b.spread * splitBins)
+		if x >= splitBins {
+			x = splitBins - 1
+		}
+		return x
+	}
+
+	var histogram [splitBins]float64
+	for _, i := range b.indexes {
+		histogram[bin(i)] += px.weight(int(i))
+	}
+
+	// The lowest and highest coordinates fall into the first and last bins,
+	// so splitting after any earlier bin leaves both halves non-empty.
+	median := 0
+	cumulative := histogram[0]
+	for median < splitBins-2 && cumulative < b.weight/2 {
+		median++
+		cumulative += histogram[median]
+	}
+
+	// Partition the indexes in place, preserving the order of the lower half
+	lower := 0
+	for x, i := range b.indexes {
+		if bin(i) <= median {
+			b.indexes[x], b.indexes[lower] = b.indexes[lower], i
+			lower++
+		}
+	}
+	return newBox(px, b.indexes[:lower]), newBox(px, b.indexes[lower:])
+}
@@ -0,0 +1,106 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestMedianCut(t *testing.T) {
+	// Each box's color is the mean of its colors
+	colors := rgba64s(opaqueBlack, opaqueWhite)
+	palette, err := medianCut(context.Background(), colors, Options{K: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	gray := color.RGBA64{0x8000, 0x8000, 0x8000, 0xffff}
+	if palette.Weight(gray) != 1 {
+		t.Errorf("expected mean color %v, got %v", gray, palette.Entries())
+	}
+
+	// Boxes of identical colors are not split
+	colors = rgba64s(opaqueBlack, opaqueBlack, opaqueBlack, opaqueWhite)
+	palette, err = medianCut(context.Background(), colors, Options{K: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != 2 {
+		t.Errorf("expected 2 colors, got %v", palette.Entries())
+	}
+	if palette.Weight(opaqueBlack) != 0.75 {
+		t.Errorf("expected weight of black to be 0.75, got %v", palette.Weight(opaqueBlack))
+	}
+
+	// Boxes are split along the axis with the widest spread, here green
+	// rather than red
+	colors = rgba64s(
+		color.RGBA{0, 0, 0, 255},
+		color.RGBA{64, 0, 0, 255},
+		color.RGBA{0, 255, 0, 255},
+		color.RGBA{64, 255, 0, 255},
+	)
+	palette, err = medianCut(context.Background(), colors, Options{K: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, c := range []color.Color{color.RGBA{32, 0, 0, 255}, color.RGBA{32, 255, 0, 255}} {
+		if palette.Weight(c) != 0.5 {
+			t.Errorf("expected weight of %v to be 0.5, got %v", c, palette.Entries())
+		}
+	}
+}
+
+func TestMedianCutWeights(t *testing.T) {
+	// With AlphaWeight, the translucent colors count for less than the
+	// opaque ones
+	colors := rgba64s(
+		color.RGBA{0, 0, 0, 255},
+		color.RGBA{0, 0, 0, 255},
+		color.RGBA{0, 0, 0, 255},
+		color.NRGBA{255, 255, 255, 85},
+		color.NRGBA{255, 255, 255, 85},
+		color.NRGBA{255, 255, 255, 85},
+	)
+	palette, err := medianCut(context.Background(), colors, Options{K: 2, Alpha: AlphaWeight})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if w := palette.Weight(color.Black); math.Abs(w-0.75) > 1e-6 {
+		t.Errorf("expected weight of black to be 0.75, got %v", w)
+	}
+}
+
+func TestExtractMedianCut(t *testing.T) {
+	img := loadTestImage(t, "testdata/resized.jpg")
+
+	if _, err := ExtractMedianCut(img, Options{}); err == nil {
+		t.Errorf("k is required, expected an error")
+	}
+
+	for _, space := range []ColorSpace{RGB, Lab} {
+		opts := Options{K: 6, ColorSpace: space, HistogramBits: DefaultHistogramBits}
+		palette, err := ExtractMedianCut(img, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if palette.Count() != 6 {
+			t.Errorf("expected 6 colors, got %d", palette.Count())
+		}
+		if !palette.Converged() || palette.Iterations() != 0 {
+			t.Errorf("expected a converged palette w/ 0 iterations")
+		}
+	}
+}
+
+func BenchmarkExtractMedianCut1080x1080(b *testing.B) {
+	img := loadTestImage(b, "testdata/original.jpg")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ExtractMedianCut(img, Options{K: 4}); err != nil {
+			b.Error(err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package palettor
+
+import "math"
+
+// A Metric measures the distance between two colors, which the clustering
+// algorithm uses to decide which cluster each color belongs to.
+type Metric interface {
+	// Space returns the color space in which the metric operates. Colors are
+	// converted into this space before clustering.
+	Space() ColorSpace
+
+	// Distance returns the distance between two colors, given as their
+	// coordinates in Space. It must be non-negative, and zero for identical
+	// colors.
+	//
+	// The distance need not be symmetric: a is the reference color, and b
+	// the color compared against it. The clustering algorithms always give
+	// the color of a cluster, such as a centroid, as a, and a pixel as b.
+	Distance(a, b [3]float64) float64
+}
+
+// A DistanceFunc returns the distance between two colors, given as their
+// coordinates in some ColorSpace. See Metric.Distance.
+type DistanceFunc func(a, b [3]float64) float64
+
+// NewMetric returns a Metric which measures distances in the given color
+// space using the given function.
+func NewMetric(space ColorSpace, distance DistanceFunc) Metric {
+	return metric{"custom", space, distance, nil}
+}
+
+// Built-in metrics
+var (
+	// EuclideanRGB is the Euclidean distance between two colors' 16-bit RGB
+	// channels. It is the default metric.
+	EuclideanRGB Metric = metric{"euclidean", RGB, euclidean, distanceSquared}
+
+	// Redmean is a weighted Euclidean distance in RGB which approximates
+	// perceptual differences better than EuclideanRGB, at almost no extra
+	// cost.
+	//
+	// https://en.wikipedia.org/wiki/Color_difference#sRGB
+	Redmean Metric = metric{"redmean", RGB, redmean, redmeanSquared}
+
+	// CIE76 is the Euclidean distance between two colors in CIELAB. It is
+	// the default metric for the Lab color space.
+	//
+	// https://en.wikipedia.org/wiki/Color_difference#CIE76
+	CIE76 Metric = metric{"cie76", Lab, euclidean, distanceSquared}
+
+	// CIE94 corrects CIE76 for the perceptual non-uniformities of CIELAB in
+	// chroma and hue, using the graphic arts weighting factors. It is not
+	// symmetric, because chroma is weighted by the chroma of the reference
+	// color.
+	//
+	// https://en.wikipedia.org/wiki/Color_difference#CIE94
+	CIE94 Metric = metric{"cie94", Lab, cie94, nil}
+
+	// CIEDE2000 is the most accurate, and the most expensive, of the CIE
+	// color difference formulas.
+	//
+	// https://en.wikipedia.org/wiki/Color_difference#CIEDE2000
+	CIEDE2000 Metric = metric{"ciede2000", Lab, ciede2000, nil}
+)
+
+type metric struct {
+	name     string
+	space    ColorSpace
+	distance DistanceFunc
+
+	// squared, if not nil, returns the square of distance, which orders
+	// colors the same way without taking a square root.
+	squared DistanceFunc
+}
+
+func (m metric) Space() ColorSpace                { return m.space }
+func (m metric) Distance(a, b [3]float64) float64 { return m.distance(a, b) }
+func (m metric) String() string                   { return m.name }
+
+// ranking returns a function which orders colors by their distance from a
+// reference color in the same way as the given metric, for finding the
+// nearest of several colors. For the built-in metrics which are the square
+// root of a sum of squares, it is the squared distance, which saves a square
+// root for every pair of colors compared.
+func ranking(m Metric) DistanceFunc {
+	if m, ok := m.(metric); ok && m.squared != nil {
+		return m.squared
+	}
+	return m.Distance
+}
+
+func euclidean(a, b point) float64 {
+	return math.Sqrt(distanceSquared(a, b))
+}
+
+// redmean weights the RGB channels according to the mean red level of the
+// two colors. The weights are defined for 8-bit channels, so the mean red
+// level is scaled accordingly, but the distance remains in 16-bit units.
+func redmean(a, b point) float64 {
+	return math.Sqrt(redmeanSquared(a, b))
+}
+
+func redmeanSquared(a, b point) float64 {
+	rMean := (a[0] + b[0]) / 2 / 0x101
+	dr := a[0] - b[0]
+	dg := a[1] - b[1]
+	db := a[2] - b[2]
+	return (2+rMean/256)*dr*dr + 4*dg*dg + (2+(255-rMean)/256)*db*db
+}
+
+// cie94 measures the distance of b from the reference color a.
+func cie94(a, b point) float64 {
+	const (
+		k1 = 0.045
+		k2 = 0.015
+	)
+	dL := a[0] - b[0]
+	c1 := math.Hypot(a[1], a[2])
+	c2 := math.Hypot(b[1], b[2])
+	dC := c1 - c2
+	da := a[1] - b[1]
+	db := a[2] - b[2]
+	dH2 := math.Max(da*da+db*db-dC*dC, 0)
+	sC := 1 + k1*c1
+	sH := 1 + k2*c1
+	return math.Sqrt(dL*dL + (dC/sC)*(dC/sC) + dH2/(sH*sH))
+}
+
+// ciede2000 follows the formulation in "The CIEDE2000 Color-Difference
+// Formula: Implementation Notes, Supplementary Test Data, and Mathematical
+// Observations" by Sharma, Wu and Dalal, with unit weighting factors.
+func ciede2000(lab1, lab2 point) float64 {
+	const pow25to7 = 6103515625 // 25^7
+	l1, a1, b1 := lab1[0], lab1[1], lab1[2]
+	l2, a2, b2 := lab2[0], lab2[1], lab2[2]
+
+	cBar := (math.Hypot(a1, b1) + math.Hypot(a2, b2)) / 2
+	cBar7 := math.Pow(cBar, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+pow25to7)))
+	a1p := (1 + g) * a1
+	a2p := (1 + g) * a2
+	c1p := math.Hypot(a1p, b1)
+	c2p := math.Hypot(a2p, b2)
+	h1p := hueAngle(a1p, b1)
+	h2p := hueAngle(a2p, b2)
+
+	dLp := l2 - l1
+	dCp := c2p - c1p
+	var dhp float64
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(radians(dhp/2))
+
+	lBarp := (l1 + l2) / 2
+	cBarp := (c1p + c2p) / 2
+	var hBarp float64
+	switch {
+	case c1p*c2p == 0:
+		hBarp = h1p + h2p
+	case math.Abs(h1p-h2p) <= 180:
+		hBarp = (h1p + h2p) / 2
+	case h1p+h2p < 360:
+		hBarp = (h1p + h2p + 360) / 2
+	default:
+		hBarp = (h1p + h2p - 360) / 2
+	}
+
+	t := 1 -
+		0.17*math.Cos(radians(hBarp-30)) +
+		0.24*math.Cos(radians(2*hBarp)) +
+		0.32*math.Cos(radians(3*hBarp+6)) -
+		0.20*math.Cos(radians(4*hBarp-63))
+	dTheta := 30 * math.Exp(-math.Pow((hBarp-275)/25, 2))
+	cBarp7 := math.Pow(cBarp, 7)
+	rC := 2 * math.Sqrt(cBarp7/(cBarp7+pow25to7))
+	lBarp50 := (lBarp - 50) * (lBarp - 50)
+	sL := 1 + 0.015*lBarp50/math.Sqrt(20+lBarp50)
+	sC := 1 + 0.045*cBarp
+	sH := 1 + 0.015*cBarp*t
+	rT := -math.Sin(radians(2*dTheta)) * rC
+
+	dL := dLp / sL
+	dC := dCp / sC
+	dH := dHp / sH
+	return math.Sqrt(dL*dL + dC*dC + dH*dH + rT*dC*dH)
+}
+
+// hueAngle returns the angle of the given a*, b* coordinates in degrees, in
+// the range [0, 360).
+func hueAngle(a, b float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,123 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// Test data from Sharma, Wu & Dalal, "The CIEDE2000 Color-Difference
+// Formula: Implementation Notes, Supplementary Test Data, and Mathematical
+// Observations"
+func TestCIEDE2000(t *testing.T) {
+	testCases := []struct {
+		a, b     point
+		expected float64
+	}{
+		{point{50, 2.6772, -79.7751}, point{50, 0, -82.7485}, 2.0425},
+		{point{50, 3.1571, -77.2803}, point{50, 0, -82.7485}, 2.8615},
+		{point{50, 0, 0}, point{50, -1, 2}, 2.3669},
+		{point{50, -1, 2}, point{50, 0, 0}, 2.3669},
+		{point{50, 2.49, -0.001}, point{50, -2.49, 0.0009}, 7.1792},
+		{point{50, 2.5, 0}, point{73, 25, -18}, 27.1492},
+		{point{50, 2.5, 0}, point{50, 3.1736, 0.5854}, 1.0000},
+		{point{60.2574, -34.0099, 36.2677}, point{60.4626, -34.1751, 39.4387}, 1.2644},
+		{point{22.7233, 20.0904, -46.6940}, point{23.0331, 14.9730, -42.5619}, 2.0373},
+		{point{90.8027, -2.0831, 1.4410}, point{91.1528, -1.6435, 0.0447}, 1.4441},
+		{point{2.0776, 0.0795, -1.1350}, point{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for _, tc := range testCases {
+		if actual := CIEDE2000.Distance(tc.a, tc.b); math.Abs(actual-tc.expected) > 0.0001 {
+			t.Errorf("CIEDE2000(%v, %v): expected %v, got %v", tc.a, tc.b, tc.expected, actual)
+		}
+	}
+}
+
+func TestCIE94(t *testing.T) {
+	a := point{50, 2.5, 0}
+	b := point{73, 25, -18}
+	expected := 34.6892
+	if actual := CIE94.Distance(a, b); math.Abs(actual-expected) > 0.0001 {
+		t.Errorf("CIE94(%v, %v): expected %v, got %v", a, b, expected, actual)
+	}
+
+	// Chroma is weighted by the chroma of the reference color, a
+	if CIE94.Distance(b, a) == CIE94.Distance(a, b) {
+		t.Errorf("CIE94: expected distance to depend on the reference color")
+	}
+}
+
+func TestRanking(t *testing.T) {
+	metrics := map[string]Metric{
+		"euclidean": EuclideanRGB,
+		"redmean":   Redmean,
+		"cie76":     CIE76,
+		"cie94":     CIE94,
+		"ciede2000": CIEDE2000,
+		"custom":    NewMetric(RGB, euclidean),
+	}
+	for name, metric := range metrics {
+		rank := ranking(metric)
+		for i := 0; i < 100; i++ {
+			a := testPoint(metric.Space(), randomColor())
+			b := testPoint(metric.Space(), randomColor())
+			c := testPoint(metric.Space(), randomColor())
+			if (rank(a, b) < rank(a, c)) != (metric.Distance(a, b) < metric.Distance(a, c)) {
+				t.Fatalf("%s: ranking of %v and %v from %v disagrees with distance", name, b, c, a)
+			}
+		}
+	}
+
+	a := testPoint(RGB, color.RGBA{0, 0, 0, 255})
+	b := testPoint(RGB, color.RGBA{255, 255, 255, 255})
+	if actual, expected := ranking(EuclideanRGB)(a, b), 3.0*0xffff*0xffff; math.Abs(actual-expected) > 1e-6 {
+		t.Errorf("EuclideanRGB: expected squared distance %v, got %v", expected, actual)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := map[string]Metric{
+		"euclidean": EuclideanRGB,
+		"redmean":   Redmean,
+		"cie76":     CIE76,
+		"cie94":     CIE94,
+		"ciede2000": CIEDE2000,
+	}
+	for name, metric := range metrics {
+		c := testPoint(metric.Space(), randomColor())
+		if d := metric.Distance(c, c); d != 0 {
+			t.Errorf("%s: distance between identical colors should be 0, got %v", name, d)
+		}
+
+		toPoint := func(c color.Color) point { return testPoint(metric.Space(), c) }
+		dark := metric.Distance(toPoint(black), toPoint(darkGray))
+		bright := metric.Distance(toPoint(black), toPoint(white))
+		if dark >= bright {
+			t.Errorf("%s: black should be closer to dark gray than to white", name)
+		}
+	}
+
+	a := testPoint(RGB, color.RGBA{0, 0, 0, 255})
+	b := testPoint(RGB, color.RGBA{255, 255, 255, 255})
+	if actual, expected := EuclideanRGB.Distance(a, b), math.Sqrt(3)*0xffff; math.Abs(actual-expected) > 1e-6 {
+		t.Errorf("EuclideanRGB: expected %v, got %v", expected, actual)
+	}
+	if actual, expected := Redmean.Distance(a, b), math.Sqrt(8+255.0/256)*0xffff; math.Abs(actual-expected) > 1e-6 {
+		t.Errorf("Redmean: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNewMetric(t *testing.T) {
+	manhattan := NewMetric(RGB, func(a, b [3]float64) float64 {
+		return math.Abs(a[0]-b[0]) + math.Abs(a[1]-b[1]) + math.Abs(a[2]-b[2])
+	})
+	colors := getPixels(loadTestImage(t, "testdata/resized.jpg"))
+	palette, err := clusterColors(context.Background(), colors, Options{K: 3, MaxIterations: 100, Seed: 1, Metric: manhattan})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != 3 {
+		t.Errorf("expected 3 colors, got %d", palette.Count())
+	}
+}
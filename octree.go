@@ -0,0 +1,190 @@
+package palettor
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// ExtractOctree finds up to opts.K dominant colors in the given image using
+// octree color quantization[1], as a faster alternative to k-means clustering
+// for large batches of images. Each color in the Palette is the weighted mean
+// of the colors merged into one leaf of the tree, and its weight is the share
+// of the image's pixels in that leaf, as with ExtractWithOptions.
+//
+// The octree always partitions colors in RGB, so opts.ColorSpace and
+// opts.Metric are only used to measure the Palette's ClusterStats.
+// opts.MaxIterations, opts.Init, opts.Centroid and opts.Restarts are ignored,
+// while opts.Alpha, opts.HistogramBits and the sampling options are honored.
+// The result is fully deterministic, and the Palette always reports zero
+// iterations and convergence.
+//
+// ExtractOctree is shorthand for the Extract method of Octree, with a
+// background context.
+//
+// [1]: https://en.wikipedia.org/wiki/Octree#Color_quantization
+func ExtractOctree(img image.Image, opts Options) (*Palette, error) {
+	return Octree{opts}.Extract(context.Background(), img)
+}
+
+// octreeDepth is the depth of the leaves of an octree, which distinguishes
+// colors by the top 8 bits of each channel.
+const octreeDepth = 8
+
+// An octreeNode is a node in an octree, which covers the colors whose
+// channels share a prefix of level bits.
+type octreeNode struct {
+	// children holds the index of each child of the node in the tree, or 0
+	// if the node has no such child. The root has index 0, so it is never a
+	// child.
+	children [8]int32
+
+	// sum accumulates the colors which belong to the node itself, either
+	// because it is a leaf or because children have been merged into it.
+	sum bucket
+
+	// weight is the total weight of the colors in the node's subtree.
+	weight float64
+}
+
+// octreeQuantize inserts the given colors into an octree, and then reduces
+// the tree to opts.K leaves by merging the lightest leaves into their parents,
+// deepest first. Merging stops as soon as opts.K leaves remain, so the palette
+// has opts.K colors unless there are fewer distinct colors than that.
+func octreeQuantize(ctx context.Context, colors []color.RGBA64, opts Options) (*Palette, error) {
+	colors, weights, counts, k, err := prepareColors(colors, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// levels holds the indexes of the nodes at each level above the leaves,
+	// in the order in which they were created.
+	var levels [octreeDepth][]int32
+	nodes := []octreeNode{{}}
+	levels[0] = append(levels[0], 0)
+	leafCount := 0
+	var totalWeight float64
+	for i, c := range colors {
+		w := 1.0
+		if weights != nil {
+			w = float64(weights[i])
+		}
+		totalWeight += w
+
+		node := int32(0)
+		for level := 0; level < octreeDepth; level++ {
+			nodes[node].weight += w
+			child := octreeChild(c, level)
+			next := nodes[node].children[child]
+			if next == 0 {
+				next = int32(len(nodes))
+				nodes = append(nodes, octreeNode{})
+				nodes[node].children[child] = next
+				if level+1 < octreeDepth {
+					levels[level+1] = append(levels[level+1], next)
+				} else {
+					leafCount++
+				}
+			}
+			node = next
+		}
+		nodes[node].weight += w
+		nodes[node].sum.add(c, w)
+	}
+
+	for level := octreeDepth - 1; level >= 0 && leafCount > k; level-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		leafCount = reduceLevel(nodes, levels[level], leafCount, k)
+	}
+
+	metric := opts.metric()
+	entries := make([]Entry, 0, leafCount)
+	points := make([]point, 0, leafCount)
+	entryIndexes := make([]int32, len(nodes))
+	for i := range nodes {
+		if sum := &nodes[i].sum; sum.weight > 0 {
+			c := sum.color()
+			entryIndexes[i] = int32(len(entries))
+			entries = append(entries, Entry{c, sum.weight / totalWeight})
+			points = append(points, metric.Space().rgbPoint(uint32(c.R), uint32(c.G), uint32(c.B)))
+		}
+	}
+
+	// Each color ends up in the deepest node on its path which remains in
+	// the tree once merged children have been removed.
+	labels := make([]int32, len(colors))
+	for i, c := range colors {
+		node := int32(0)
+		for level := 0; level < octreeDepth; level++ {
+			next := nodes[node].children[octreeChild(c, level)]
+			if next == 0 {
+				break
+			}
+			node = next
+		}
+		labels[i] = entryIndexes[node]
+	}
+
+	px := newPixels(colors, weights, counts, metric.Space())
+	stats, inertia := clusterStats(px, labels, points, metric, opts.workers())
+	return newPalette(entries, stats, inertia, 0, true), nil
+}
+
+// octreeChild returns the index of the child of a node at the given level
+// which covers the given color, given by the next bit of each channel.
+func octreeChild(c color.RGBA64, level int) int {
+	shift := 15 - uint(level)
+	return int(c.R>>shift&1)<<2 | int(c.G>>shift&1)<<1 | int(c.B>>shift&1)
+}
+
+// reduceLevel merges the children of the nodes at one level of an octree,
+// whose children must all be leaves, into their parents until only k leaves
+// remain. The lightest nodes are reduced first, and their lightest children
+// are merged first. It returns the number of leaves remaining.
+func reduceLevel(nodes []octreeNode, level []int32, leafCount, k int) int {
+	parents := make([]int32, len(level))
+	copy(parents, level)
+	sort.SliceStable(parents, func(i, j int) bool {
+		return nodes[parents[i]].weight < nodes[parents[j]].weight
+	})
+
+	for _, parent := range parents {
+		node := &nodes[parent]
+		// Insertion sort the (at most 8) children by weight
+		var children [8]int32
+		n := 0
+		for _, child := range node.children {
+			if child == 0 {
+				continue
+			}
+			x := n
+			for ; x > 0 && nodes[children[x-1]].weight > nodes[child].weight; x-- {
+				children[x] = children[x-1]
+			}
+			children[x] = child
+			n++
+		}
+
+		for _, child := range children[:n] {
+			if leafCount <= k {
+				return leafCount
+			}
+			// The parent only becomes a leaf with the first merged child,
+			// so that merge leaves the number of leaves unchanged.
+			if node.sum.weight > 0 {
+				leafCount--
+			}
+			node.sum.merge(&nodes[child].sum)
+			nodes[child].sum = bucket{}
+			for slot := range node.children {
+				if node.children[slot] == child {
+					node.children[slot] = 0
+				}
+			}
+		}
+	}
+	return leafCount
+}
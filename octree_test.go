@@ -0,0 +1,56 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"testing"
+)
+
+func TestOctreeQuantize(t *testing.T) {
+	// The closest colors are merged first, into their weighted mean
+	colors := rgba64s(opaqueBlack, opaqueBlack, opaqueBlack, color.RGBA{1, 1, 1, 255}, opaqueWhite)
+	palette, err := octreeQuantize(context.Background(), colors, Options{K: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nearlyBlack := color.RGBA64{64, 64, 64, 0xffff}
+	if palette.Weight(nearlyBlack) != 0.8 {
+		t.Errorf("expected weight of %v to be 0.8, got %v", nearlyBlack, palette.Entries())
+	}
+	if palette.Weight(opaqueWhite) != 0.2 {
+		t.Errorf("expected weight of white to be 0.2, got %v", palette.Entries())
+	}
+}
+
+func TestExtractOctree(t *testing.T) {
+	img := loadTestImage(t, "testdata/resized.jpg")
+
+	if _, err := ExtractOctree(img, Options{}); err == nil {
+		t.Errorf("k is required, expected an error")
+	}
+
+	for k := 1; k <= 16; k++ {
+		palette, err := ExtractOctree(img, Options{K: k})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if palette.Count() != k {
+			t.Errorf("expected %d colors, got %d", k, palette.Count())
+		}
+		if !palette.Converged() || palette.Iterations() != 0 {
+			t.Errorf("expected a converged palette w/ 0 iterations")
+		}
+	}
+}
+
+func BenchmarkExtractOctree1080x1080(b *testing.B) {
+	img := loadTestImage(b, "testdata/original.jpg")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ExtractOctree(img, Options{K: 4}); err != nil {
+			b.Error(err)
+		}
+	}
+}
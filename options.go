@@ -0,0 +1,274 @@
+package palettor
+
+import (
+	"fmt"
+	"image/color"
+	"runtime"
+	"time"
+)
+
+// DefaultMaxIterations is the maximum number of k-means iterations used when
+// Options.MaxIterations is not set.
+const DefaultMaxIterations = 100
+
+// An InitMethod selects how the initial k-means centroids are chosen.
+type InitMethod int
+
+// Supported k-means initialization methods
+const (
+	// InitRandom picks k distinct pixels uniformly at random.
+	InitRandom InitMethod = iota
+
+	// InitKMeansPlusPlus picks the first centroid at random and each
+	// subsequent centroid with probability proportional to its squared
+	// distance from the nearest centroid already chosen, which spreads the
+	// initial centroids out across the image's colors.
+	//
+	// https://en.wikipedia.org/wiki/K-means%2B%2B
+	InitKMeansPlusPlus
+)
+
+// A CentroidMethod selects how the centroid of each cluster is computed from
+// the colors in the cluster.
+type CentroidMethod int
+
+// Supported centroid methods
+const (
+	// CentroidMedoid uses the color in each cluster closest to the mean of
+	// the cluster, so that every color in the palette literally occurs in
+	// the image.
+	CentroidMedoid CentroidMethod = iota
+
+	// CentroidMean uses the mean of the colors in each cluster, as in the
+	// standard k-means algorithm. Each iteration is cheaper than with
+	// CentroidMedoid, but the resulting colors may not occur in the image.
+	CentroidMean
+)
+
+// An EmptyClusterPolicy selects what happens to a k-means cluster when no
+// pixels are nearer to its centroid than to any other.
+type EmptyClusterPolicy int
+
+// Supported empty cluster policies
+const (
+	// EmptyDrop drops empty clusters, so the palette may have fewer than k
+	// colors.
+	EmptyDrop EmptyClusterPolicy = iota
+
+	// EmptyReseedFarthest moves the centroid of an empty cluster to the
+	// pixel furthest from its own centroid.
+	EmptyReseedFarthest
+
+	// EmptySplitLargest moves the centroid of an empty cluster to the pixel
+	// in the heaviest cluster furthest from that cluster's centroid, which
+	// splits the heaviest cluster in two.
+	EmptySplitLargest
+)
+
+// Options configures how a Palette is extracted from an image. Apart from K,
+// the zero value of every field selects a sensible default.
+type Options struct {
+	// K is the number of colors to extract.
+	K int
+
+	// MaxIterations is the number of k-means iterations after which the
+	// algorithm gives up on converging. Defaults to DefaultMaxIterations.
+	MaxIterations int
+
+	// BatchSize, if non-zero, switches k-means to mini-batch k-means, in
+	// which each iteration moves the centroids towards a batch of BatchSize
+	// pixels sampled at random, followed by a single full iteration over
+	// every pixel. This is much faster for very large images, at some cost
+	// in the quality of the clusters. Pixels are sampled with the random
+	// number generator seeded by Seed. Mini-batch k-means rarely converges
+	// exactly, so it is best combined with a Tolerance.
+	BatchSize int
+
+	// Tolerance is the largest distance, measured by Metric, that any
+	// centroid may move in an iteration for k-means to be considered
+	// converged. For example, with CIE76 a tolerance of 1 stops once no
+	// centroid moves by more than 1 ΔE. Defaults to 0, which requires the
+	// centroids to stop moving altogether.
+	Tolerance float64
+
+	// Seed seeds the random number generator used by the clustering
+	// algorithm. Extracting a palette from the same image with the same
+	// non-zero Seed and Options always gives the same result. If zero, a
+	// seed is derived from the current time.
+	Seed int64
+
+	// Init selects how the initial centroids are chosen. Defaults to
+	// InitRandom.
+	Init InitMethod
+
+	// ColorSpace is the color space in which colors are clustered when no
+	// Metric is given. Defaults to RGB.
+	ColorSpace ColorSpace
+
+	// Metric measures the distance between colors. Colors are clustered in
+	// the metric's own color space. Defaults to the Euclidean distance in
+	// ColorSpace, i.e. EuclideanRGB or CIE76.
+	Metric Metric
+
+	// Centroid selects how the centroid of each cluster is computed.
+	// Defaults to CentroidMedoid.
+	Centroid CentroidMethod
+
+	// EmptyClusters selects what happens to empty clusters. With
+	// EmptyReseedFarthest or EmptySplitLargest, the palette has exactly K
+	// colors whenever there are at least K distinct colors to cluster.
+	// Defaults to EmptyDrop.
+	EmptyClusters EmptyClusterPolicy
+
+	// Alpha selects how transparent and translucent pixels are treated.
+	// Palette weights are computed over the pixels that are included.
+	// Defaults to AlphaIgnore.
+	Alpha AlphaPolicy
+
+	// AlphaThreshold is the alpha, in the range [0, 1], at or below which
+	// pixels are skipped under AlphaSkip. Defaults to 0, which skips only
+	// fully transparent pixels.
+	AlphaThreshold float64
+
+	// Background is the color over which pixels are composited under
+	// AlphaComposite. Defaults to white.
+	Background color.Color
+
+	// Timeout limits how long extraction may take, in addition to any
+	// deadline on the context passed to ExtractContext. Zero means no limit.
+	Timeout time.Duration
+
+	// Workers is the number of goroutines across which each k-means
+	// iteration is spread. Results do not depend on the number of workers.
+	// Defaults to runtime.GOMAXPROCS(0).
+	Workers int
+
+	// HistogramBits, if non-zero, buckets pixels into a histogram with this
+	// many bits per channel before clustering, and clusters the mean colors
+	// of the buckets weighted by their pixel counts instead of individual
+	// pixels. This makes clustering large images much faster, at some cost
+	// in precision; with CentroidMedoid, palette colors are the mean colors
+	// of buckets rather than exact pixel colors. Must be at most 8. See
+	// DefaultHistogramBits.
+	HistogramBits int
+
+	// MaxPixels, if non-zero, limits the number of pixels sampled from the
+	// image, which bounds the cost of extraction regardless of image size.
+	// See DefaultMaxPixels.
+	MaxPixels int
+
+	// Sampling selects how pixels are sampled from images with more than
+	// MaxPixels pixels. Defaults to SampleGrid.
+	Sampling SamplingMethod
+
+	// Stride, if greater than 1, samples only every Stride-th pixel in each
+	// direction. If MaxPixels is also given, the stride is widened as
+	// necessary to stay within MaxPixels.
+	Stride int
+
+	// Restarts is the number of times k-means is run, with consecutive
+	// seeds starting from Seed, keeping the palette with the lowest inertia.
+	// More restarts make a poor palette from an unlucky initialization less
+	// likely, at the cost of proportionally more work. Defaults to 1.
+	Restarts int
+
+	// ConcurrentRestarts runs restarts concurrently instead of one after
+	// another. Results do not depend on whether restarts run concurrently.
+	ConcurrentRestarts bool
+}
+
+// metric returns the metric to use for clustering.
+func (o Options) metric() Metric {
+	if o.Metric != nil {
+		return o.Metric
+	}
+	if o.ColorSpace == Lab {
+		return CIE76
+	}
+	return EuclideanRGB
+}
+
+// workers returns the number of goroutines to use for clustering.
+func (o Options) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// seed returns the seed for the random number generator, derived from the
+// current time if Seed is zero.
+func (o Options) seed() int64 {
+	if o.Seed != 0 {
+		return o.Seed
+	}
+	return time.Now().UnixNano()
+}
+
+// restarts returns the number of times to run k-means.
+func (o Options) restarts() int {
+	if o.Restarts > 0 {
+		return o.Restarts
+	}
+	return 1
+}
+
+// withDefaults validates a set of options and fills in defaults for any
+// unset fields.
+func (o Options) withDefaults() (Options, error) {
+	if o.K < 1 {
+		return o, fmt.Errorf("k must be positive (got %d)", o.K)
+	}
+	if o.MaxIterations < 0 {
+		return o, fmt.Errorf("max iterations must not be negative (got %d)", o.MaxIterations)
+	}
+	if o.BatchSize < 0 {
+		return o, fmt.Errorf("batch size must not be negative (got %d)", o.BatchSize)
+	}
+	if o.Tolerance < 0 {
+		return o, fmt.Errorf("tolerance must not be negative (got %v)", o.Tolerance)
+	}
+	if o.Init != InitRandom && o.Init != InitKMeansPlusPlus {
+		return o, fmt.Errorf("unknown init method %d", o.Init)
+	}
+	if o.ColorSpace != RGB && o.ColorSpace != Lab {
+		return o, fmt.Errorf("unknown color space %d", o.ColorSpace)
+	}
+	if o.Centroid != CentroidMedoid && o.Centroid != CentroidMean {
+		return o, fmt.Errorf("unknown centroid method %d", o.Centroid)
+	}
+	if o.EmptyClusters < EmptyDrop || o.EmptyClusters > EmptySplitLargest {
+		return o, fmt.Errorf("unknown empty cluster policy %d", o.EmptyClusters)
+	}
+	if o.Alpha < AlphaIgnore || o.Alpha > AlphaComposite {
+		return o, fmt.Errorf("unknown alpha policy %d", o.Alpha)
+	}
+	if o.AlphaThreshold < 0 || o.AlphaThreshold > 1 {
+		return o, fmt.Errorf("alpha threshold must be in the range [0, 1] (got %v)", o.AlphaThreshold)
+	}
+	if o.Timeout < 0 {
+		return o, fmt.Errorf("timeout must not be negative (got %s)", o.Timeout)
+	}
+	if o.Workers < 0 {
+		return o, fmt.Errorf("workers must not be negative (got %d)", o.Workers)
+	}
+	if o.HistogramBits < 0 || o.HistogramBits > 8 {
+		return o, fmt.Errorf("histogram bits must be in the range [0, 8] (got %d)", o.HistogramBits)
+	}
+	if o.MaxPixels < 0 {
+		return o, fmt.Errorf("max pixels must not be negative (got %d)", o.MaxPixels)
+	}
+	if o.Sampling != SampleGrid && o.Sampling != SampleRandom {
+		return o, fmt.Errorf("unknown sampling method %d", o.Sampling)
+	}
+	if o.Stride < 0 {
+		return o, fmt.Errorf("stride must not be negative (got %d)", o.Stride)
+	}
+	if o.Restarts < 0 {
+		return o, fmt.Errorf("restarts must not be negative (got %d)", o.Restarts)
+	}
+	if o.MaxIterations == 0 {
+		o.MaxIterations = DefaultMaxIterations
+	}
+	return o, nil
+}
@@ -0,0 +1,199 @@
+package palettor
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// A SortOrder selects the order of the entries returned by Palette.Entries
+// and the colors returned by Palette.Colors.
+type SortOrder int
+
+// Supported sort orders
+const (
+	// ByWeight sorts entries from the least to the most dominant. This is
+	// the default order.
+	ByWeight SortOrder = iota
+
+	// ByWeightDescending sorts entries from the most to the least dominant.
+	ByWeightDescending
+
+	// ByHue sorts entries by hue, around the color wheel from red through
+	// yellow, green, cyan, blue and magenta. Achromatic colors, i.e. black,
+	// white and grays, have no hue and come first.
+	ByHue
+
+	// ByLuminance sorts entries from the darkest to the lightest, by their
+	// relative luminance.
+	ByLuminance
+
+	// ByPaletteWalk starts from the darkest entry and repeatedly moves on to
+	// the remaining entry perceptually closest to the last, as measured by
+	// CIEDE2000, so that neighboring swatches blend into each other.
+	ByPaletteWalk
+)
+
+// sortEntries returns a copy of the given entries sorted by the given orders,
+// each of which breaks ties left by the ones before it. Any remaining ties
+// are broken by color, so the result does not depend on the order of the
+// given entries.
+func sortEntries(entries []Entry, orders []SortOrder) []Entry {
+	if len(orders) == 0 {
+		orders = []SortOrder{ByWeight}
+	}
+
+	// Start from entries sorted by color, which breaks any ties
+	entries = append([]Entry(nil), entries...)
+	sort.SliceStable(entries, func(a, b int) bool {
+		return colorLess(normalizeColor(entries[a].Color), normalizeColor(entries[b].Color))
+	})
+
+	keys := make([][]float64, len(entries))
+	for i := range keys {
+		keys[i] = make([]float64, len(orders))
+	}
+	for j, order := range orders {
+		for i, key := range sortKeys(entries, order) {
+			keys[i][j] = key
+		}
+	}
+
+	indexes := make([]int, len(entries))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		ka, kb := keys[indexes[a]], keys[indexes[b]]
+		for j := range ka {
+			if ka[j] != kb[j] {
+				return ka[j] < kb[j]
+			}
+		}
+		return false
+	})
+
+	sorted := make([]Entry, len(entries))
+	for i, x := range indexes {
+		sorted[i] = entries[x]
+	}
+	return sorted
+}
+
+// sortKeys returns a key for each of the given entries, such that sorting
+// the keys in ascending order sorts the entries in the given order.
+func sortKeys(entries []Entry, order SortOrder) []float64 {
+	keys := make([]float64, len(entries))
+	switch order {
+	case ByWeightDescending:
+		for i, entry := range entries {
+			keys[i] = -entry.Weight
+		}
+	case ByHue:
+		for i, entry := range entries {
+			keys[i] = hue(entry.Color)
+		}
+	case ByLuminance:
+		for i, entry := range entries {
+			keys[i] = luminance(entry.Color)
+		}
+	case ByPaletteWalk:
+		for i, x := range paletteWalk(entries) {
+			keys[x] = float64(i)
+		}
+	default:
+		for i, entry := range entries {
+			keys[i] = entry.Weight
+		}
+	}
+	return keys
+}
+
+// paletteWalk returns the indexes of the given entries in the order in which
+// a greedy nearest-neighbor walk through Lab visits them, starting from the
+// darkest. Ties are broken in favor of the earliest entry.
+func paletteWalk(entries []Entry) []int {
+	points := make([]point, len(entries))
+	for i, entry := range entries {
+		r, g, b, _ := entry.Color.RGBA()
+		points[i] = rgbToLab(r, g, b)
+	}
+
+	visited := make([]bool, len(entries))
+	walk := make([]int, 0, len(entries))
+	for len(walk) < len(entries) {
+		next := -1
+		for i, p := range points {
+			if visited[i] {
+				continue
+			}
+			if next < 0 {
+				next = i
+				continue
+			}
+			if len(walk) == 0 {
+				if p[0] < points[next][0] {
+					next = i
+				}
+				continue
+			}
+			last := points[walk[len(walk)-1]]
+			if ciede2000(last, p) < ciede2000(last, points[next]) {
+				next = i
+			}
+		}
+		visited[next] = true
+		walk = append(walk, next)
+	}
+	return walk
+}
+
+// hue returns the HSL hue of a color in degrees in the range [0, 360), or -1
+// if the color is achromatic.
+func hue(c color.Color) float64 {
+	ri, gi, bi, _ := c.RGBA()
+	r, g, b := float64(ri), float64(gi), float64(bi)
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	if hi == lo {
+		return -1
+	}
+
+	var h float64
+	switch hi {
+	case r:
+		h = (g - b) / (hi - lo)
+	case g:
+		h = 2 + (b-r)/(hi-lo)
+	default:
+		h = 4 + (r-g)/(hi-lo)
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// luminance returns the relative luminance of a color in the range [0, 1].
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return 0.2126*linearize(float64(r)/0xffff) +
+		0.7152*linearize(float64(g)/0xffff) +
+		0.0722*linearize(float64(b)/0xffff)
+}
+
+// colorLess orders colors by their channels, for breaking ties
+// deterministically.
+func colorLess(a, b color.RGBA64) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	if a.B != b.B {
+		return a.B < b.B
+	}
+	return a.A < b.A
+}
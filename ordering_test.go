@@ -0,0 +1,93 @@
+package palettor
+
+import (
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestPaletteSortOrders(t *testing.T) {
+	darkRed := color.RGBA{128, 0, 0, 255}
+	palette := newPalette([]Entry{
+		{opaqueWhite, 0.1},
+		{opaqueBlue, 0.3},
+		{opaqueRed, 0.2},
+		{opaqueBlack, 0.15},
+		{opaqueGreen, 0.05},
+		{darkRed, 0.2},
+	}, nil, 0, 1, true)
+
+	testCases := []struct {
+		order    []SortOrder
+		expected []color.Color
+	}{
+		{nil, []color.Color{opaqueGreen, opaqueWhite, opaqueBlack, darkRed, opaqueRed, opaqueBlue}},
+		{[]SortOrder{ByWeight}, []color.Color{opaqueGreen, opaqueWhite, opaqueBlack, darkRed, opaqueRed, opaqueBlue}},
+		{[]SortOrder{ByWeightDescending}, []color.Color{opaqueBlue, darkRed, opaqueRed, opaqueBlack, opaqueWhite, opaqueGreen}},
+		{[]SortOrder{ByWeightDescending, ByLuminance}, []color.Color{opaqueBlue, darkRed, opaqueRed, opaqueBlack, opaqueWhite, opaqueGreen}},
+		{[]SortOrder{ByHue}, []color.Color{opaqueBlack, opaqueWhite, darkRed, opaqueRed, opaqueGreen, opaqueBlue}},
+		{[]SortOrder{ByHue, ByWeightDescending}, []color.Color{opaqueBlack, opaqueWhite, darkRed, opaqueRed, opaqueGreen, opaqueBlue}},
+		{[]SortOrder{ByLuminance}, []color.Color{opaqueBlack, darkRed, opaqueBlue, opaqueRed, opaqueGreen, opaqueWhite}},
+		{[]SortOrder{ByPaletteWalk}, []color.Color{opaqueBlack, darkRed, opaqueRed, opaqueWhite, opaqueGreen, opaqueBlue}},
+	}
+	for _, tc := range testCases {
+		expected := make([]color.Color, len(tc.expected))
+		for i, c := range tc.expected {
+			expected[i] = normalizeColor(c)
+		}
+		if colors := palette.Colors(tc.order...); !reflect.DeepEqual(colors, expected) {
+			t.Errorf("order %v: expected colors %v, got %v", tc.order, expected, colors)
+		}
+		entries := palette.Entries(tc.order...)
+		for i, entry := range entries {
+			if entry.Color != expected[i] || entry.Weight != palette.Weight(entry.Color) {
+				t.Errorf("order %v: expected entry %d to be %v, got %v", tc.order, i, expected[i], entry)
+			}
+		}
+	}
+}
+
+func TestPaletteSortDeterministic(t *testing.T) {
+	entries := []Entry{
+		{color.RGBA{255, 0, 0, 255}, 0.25},
+		{color.RGBA{0, 255, 0, 255}, 0.25},
+		{color.RGBA{0, 0, 255, 255}, 0.25},
+		{color.RGBA{128, 128, 128, 255}, 0.25},
+	}
+	reversed := make([]Entry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+
+	a := newPalette(entries, nil, 0, 1, true)
+	b := newPalette(reversed, nil, 0, 1, true)
+	for _, order := range []SortOrder{ByWeight, ByWeightDescending, ByHue, ByLuminance, ByPaletteWalk} {
+		if !reflect.DeepEqual(a.Entries(order), b.Entries(order)) {
+			t.Errorf("order %d: expected the same entries regardless of palette order, got %v and %v", order, a.Entries(order), b.Entries(order))
+		}
+	}
+	if !reflect.DeepEqual(a.Colors(), b.Colors()) {
+		t.Errorf("expected the same colors regardless of palette order, got %v and %v", a.Colors(), b.Colors())
+	}
+}
+
+func TestHue(t *testing.T) {
+	testCases := []struct {
+		c        color.Color
+		expected float64
+	}{
+		{color.RGBA{0, 0, 0, 255}, -1},
+		{color.RGBA{128, 128, 128, 255}, -1},
+		{color.RGBA{255, 0, 0, 255}, 0},
+		{color.RGBA{255, 255, 0, 255}, 60},
+		{color.RGBA{0, 255, 0, 255}, 120},
+		{color.RGBA{0, 255, 255, 255}, 180},
+		{color.RGBA{0, 0, 255, 255}, 240},
+		{color.RGBA{255, 0, 255, 255}, 300},
+	}
+	for _, tc := range testCases {
+		if h := hue(tc.c); !approxEqual(h, tc.expected) {
+			t.Errorf("expected hue of %v to be %v, got %v", tc.c, tc.expected, h)
+		}
+	}
+}
@@ -2,41 +2,87 @@ package palettor
 
 import (
 	"image/color"
-	"sort"
+	"math"
 )
 
 // A Palette represents the dominant colors extracted from an image, as a
 // mapping from color to the weight of that color's cluster. The weight can be
 // used as an approximation for that color's relative dominance in an image.
+//
+// Colors are stored as color.RGBA64 values, so any color with the same 16-bit
+// alpha-premultiplied channels may be used to look up a color's weight.
 type Palette struct {
-	colorWeights map[color.Color]float64
-	converged    bool
-	iterations   int
+	entries    []Entry
+	stats      []ClusterStats
+	inertia    float64
+	converged  bool
+	iterations int
+	restarts   int
 }
 
 // Entry is a color and its weight in a Palette
 type Entry struct {
-	Color  color.Color `json:"color"`
-	Weight float64     `json:"weight"`
+	Color  color.Color
+	Weight float64
 }
 
-// Entries returns a slice of Entry structs, sorted by weight
-func (p *Palette) Entries() []Entry {
-	entries := make([]Entry, p.Count())
-	i := 0
-	for color, weight := range p.colorWeights {
-		entries[i] = Entry{color, weight}
-		i++
+// newPalette creates a Palette from the given entries, normalizing their
+// colors and combining the weights and stats of any entries with equal
+// colors. The order of the entries is preserved. The stats line up with the
+// entries, and may be nil if they are unknown.
+func newPalette(entries []Entry, stats []ClusterStats, inertia float64, iterations int, converged bool) *Palette {
+	p := &Palette{
+		entries:    make([]Entry, 0, len(entries)),
+		stats:      make([]ClusterStats, 0, len(entries)),
+		inertia:    inertia,
+		iterations: iterations,
+		converged:  converged,
+		restarts:   1,
 	}
-	sort.Sort(byWeight(entries))
-	return entries
+	for j, entry := range entries {
+		var s ClusterStats
+		if stats != nil {
+			s = stats[j]
+		}
+		c := normalizeColor(entry.Color)
+		if i := p.index(c); i >= 0 {
+			p.stats[i] = mergeStats(p.stats[i], p.entries[i].Weight, s, entry.Weight)
+			p.entries[i].Weight += entry.Weight
+			continue
+		}
+		p.entries = append(p.entries, Entry{c, entry.Weight})
+		p.stats = append(p.stats, s)
+	}
+	return p
 }
 
-// Colors returns a slice of the colors that comprise a Palette.
-func (p *Palette) Colors() []color.Color {
-	var colors []color.Color
-	for color := range p.colorWeights {
-		colors = append(colors, color)
+// mergeStats combines the stats of two clusters with the given weights.
+func mergeStats(a ClusterStats, aWeight float64, b ClusterStats, bWeight float64) ClusterStats {
+	merged := ClusterStats{
+		Count:       a.Count + b.Count,
+		MaxDistance: math.Max(a.MaxDistance, b.MaxDistance),
+	}
+	if aWeight+bWeight > 0 {
+		merged.Variance = (aWeight*a.Variance + bWeight*b.Variance) / (aWeight + bWeight)
+	}
+	return merged
+}
+
+// Entries returns a slice of Entry structs, sorted in the given order. If
+// more than one order is given, each breaks ties left by the ones before it.
+// Defaults to ByWeight, i.e. from the least to the most dominant color.
+// Remaining ties are broken by color, so the order is deterministic.
+func (p *Palette) Entries(order ...SortOrder) []Entry {
+	return sortEntries(p.entries, order)
+}
+
+// Colors returns a slice of the colors that comprise a Palette, in the same
+// order as Entries.
+func (p *Palette) Colors(order ...SortOrder) []color.Color {
+	entries := p.Entries(order...)
+	colors := make([]color.Color, len(entries))
+	for i, entry := range entries {
+		colors[i] = entry.Color
 	}
 	return colors
 }
@@ -49,7 +95,7 @@ func (p *Palette) Converged() bool {
 
 // Count returns the number of colors in a Palette.
 func (p *Palette) Count() int {
-	return len(p.colorWeights)
+	return len(p.entries)
 }
 
 // Iterations returns the number of iterations required to extract the colors
@@ -58,15 +104,61 @@ func (p *Palette) Iterations() int {
 	return p.iterations
 }
 
+// Restarts returns the number of times the algorithm was run to extract a
+// Palette, of which the run with the lowest inertia was kept. See
+// Options.Restarts.
+func (p *Palette) Restarts() int {
+	return p.restarts
+}
+
 // Weight returns the weight of a color in a Palette as a float in the range
-// [0, 1], or 0 if a given color is not found.
+// [0, 1], or 0 if a given color is nil or not found.
 func (p *Palette) Weight(c color.Color) float64 {
-	return p.colorWeights[c]
+	if i := p.find(c); i >= 0 {
+		return p.entries[i].Weight
+	}
+	return 0
 }
 
-// implement sort.Interface
-type byWeight []Entry
+// Stats returns the statistics of the cluster of a color in a Palette, or
+// zero stats if the color is nil or not found.
+func (p *Palette) Stats(c color.Color) ClusterStats {
+	if i := p.find(c); i >= 0 {
+		return p.stats[i]
+	}
+	return ClusterStats{}
+}
+
+// Inertia returns the weighted sum of the squared distances between every
+// pixel and the color of its cluster, also known as the within-cluster sum of
+// squares, measured by the metric given in Options. Lower inertia means
+// tighter clusters, but inertia tends to shrink as the number of colors grows.
+func (p *Palette) Inertia() float64 {
+	return p.inertia
+}
+
+// find returns the index of the entry with the given color, compared by value,
+// or -1 if there is no such entry or the color is nil.
+func (p *Palette) find(c color.Color) int {
+	if c == nil {
+		return -1
+	}
+	return p.index(normalizeColor(c))
+}
 
-func (a byWeight) Len() int           { return len(a) }
-func (a byWeight) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a byWeight) Less(i, j int) bool { return a[i].Weight < a[j].Weight }
+// index returns the index of the entry with the given normalized color, or -1
+// if there is no such entry. Palettes are small, so a linear search is
+// cheaper than maintaining a map.
+func (p *Palette) index(c color.RGBA64) int {
+	for i, entry := range p.entries {
+		if entry.Color == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeColor converts a color to a comparable color.RGBA64 value.
+func normalizeColor(c color.Color) color.RGBA64 {
+	return color.RGBA64Model.Convert(c).(color.RGBA64)
+}
@@ -7,19 +7,15 @@ import (
 )
 
 func TestPalette(t *testing.T) {
-	colorWeights := map[color.Color]float64{
-		black: 0.75,
-		white: 0.25,
+	entries := []Entry{
+		{black, 0.75},
+		{white, 0.25},
 	}
 	iterations := 1
 	converged := true
-	palette := &Palette{
-		colorWeights: colorWeights,
-		converged:    converged,
-		iterations:   iterations,
-	}
+	palette := newPalette(entries, nil, 0, iterations, converged)
 
-	if palette.Count() != len(colorWeights) {
+	if palette.Count() != len(entries) {
 		t.Errorf("wrong number of colors in palette")
 	}
 
@@ -38,25 +34,90 @@ func TestPalette(t *testing.T) {
 		t.Errorf("wrong weight for unknown color")
 	}
 
-	for _, color := range palette.Colors() {
-		found := false
-		for inputColor := range colorWeights {
-			if color == inputColor {
-				found = true
-				break
-			}
-		}
-		if !found {
-			t.Errorf("missing color %v from palette", color)
-		}
+	// colors are returned in the same order as entries
+	expectedColors := []color.Color{normalizeColor(white), normalizeColor(black)}
+	if colors := palette.Colors(); !reflect.DeepEqual(colors, expectedColors) {
+		t.Errorf("expected colors %v, got %v", expectedColors, colors)
 	}
 
 	// ensure entries are sorted by weight
 	expectedEntries := []Entry{
-		{white, 0.25},
-		{black, 0.75},
+		{normalizeColor(white), 0.25},
+		{normalizeColor(black), 0.75},
 	}
 	if entries := palette.Entries(); !reflect.DeepEqual(entries, expectedEntries) {
 		t.Errorf("expected entries %v, got %v", expectedEntries, entries)
 	}
 }
+
+func TestPaletteWeightByValue(t *testing.T) {
+	palette := newPalette([]Entry{
+		{&color.RGBA{255, 0, 0, 255}, 0.5},
+		{&color.RGBA64{0, 0, 0xffff, 0xffff}, 0.5},
+	}, nil, 0, 1, true)
+
+	// Equal colors are found regardless of their type or identity
+	for _, c := range []color.Color{
+		color.RGBA{255, 0, 0, 255},
+		&color.RGBA{255, 0, 0, 255},
+		color.NRGBA{255, 0, 0, 255},
+		color.RGBA64{0xffff, 0, 0, 0xffff},
+	} {
+		if palette.Weight(c) != 0.5 {
+			t.Errorf("expected weight 0.5 for %#v, got %v", c, palette.Weight(c))
+		}
+	}
+	if palette.Weight(color.NRGBA{0, 0, 255, 255}) != 0.5 {
+		t.Errorf("expected weight 0.5 for blue")
+	}
+}
+
+func TestPaletteNilColor(t *testing.T) {
+	// A transparent black entry must not match a nil color
+	palette := newPalette([]Entry{
+		{color.RGBA64{}, 0.5},
+		{color.RGBA64{0xffff, 0xffff, 0xffff, 0xffff}, 0.5},
+	}, []ClusterStats{{Count: 1}, {Count: 1}}, 0, 1, true)
+
+	if w := palette.Weight(nil); w != 0 {
+		t.Errorf("expected weight 0 for nil color, got %v", w)
+	}
+	if s := palette.Stats(nil); s != (ClusterStats{}) {
+		t.Errorf("expected zero stats for nil color, got %#v", s)
+	}
+}
+
+func TestPaletteDuplicateColors(t *testing.T) {
+	palette := newPalette([]Entry{
+		{color.RGBA{255, 0, 0, 255}, 0.25},
+		{color.RGBA{0, 0, 0, 255}, 0.5},
+		{&color.RGBA{255, 0, 0, 255}, 0.25},
+	}, nil, 0, 1, true)
+
+	if palette.Count() != 2 {
+		t.Errorf("expected equal colors to be combined, got %v", palette.Entries())
+	}
+	if palette.Weight(color.RGBA{255, 0, 0, 255}) != 0.5 {
+		t.Errorf("expected combined weight 0.5, got %v", palette.Weight(color.RGBA{255, 0, 0, 255}))
+	}
+}
+
+func TestPaletteDuplicateColorStats(t *testing.T) {
+	palette := newPalette([]Entry{
+		{color.RGBA{255, 0, 0, 255}, 0.25},
+		{color.RGBA{0, 0, 0, 255}, 0.5},
+		{&color.RGBA{255, 0, 0, 255}, 0.25},
+	}, []ClusterStats{
+		{Count: 1, Variance: 2, MaxDistance: 3},
+		{Count: 2, Variance: 1, MaxDistance: 1},
+		{Count: 3, Variance: 4, MaxDistance: 2},
+	}, 5, 1, true)
+
+	expected := ClusterStats{Count: 4, Variance: 3, MaxDistance: 3}
+	if stats := palette.Stats(color.RGBA{255, 0, 0, 255}); stats != expected {
+		t.Errorf("expected combined stats %+v, got %+v", expected, stats)
+	}
+	if palette.Inertia() != 5 {
+		t.Errorf("expected inertia 5, got %v", palette.Inertia())
+	}
+}
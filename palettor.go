@@ -1,8 +1,10 @@
 // Package palettor provides a way to extract the color palette from an image
-// using k-means clustering.
+// using k-means clustering, or alternatively median cut or octree
+// quantization.
 package palettor
 
 import (
+	"context"
 	"image"
 	"image/color"
 )
@@ -10,20 +12,59 @@ import (
 // Extract finds the k most dominant colors in the given image using the
 // "standard" k-means clustering algorithm. It returns a Palette, after running
 // the algorithm up to maxIterations times.
+//
+// A maxIterations of 0 runs up to DefaultMaxIterations iterations, and a
+// negative maxIterations is an error. (Before Options were introduced, 0 ran
+// no iterations at all and negative values were accepted.)
+//
+// Extract is shorthand for ExtractWithOptions with K and MaxIterations set,
+// and with MaxPixels set to DefaultMaxPixels, so that large images are
+// sampled rather than processed in full: an image with more than 40,000
+// pixels is clustered from a grid of at most 40,000 of them. Earlier versions
+// clustered every pixel, leaving callers to resize large images themselves.
+// To cluster every pixel, use ExtractWithOptions, which does not sample
+// unless MaxPixels is set.
 func Extract(k, maxIterations int, img image.Image) (*Palette, error) {
-	return clusterColors(k, maxIterations, getColors(img))
+	return ExtractWithOptions(img, Options{
+		K:             k,
+		MaxIterations: maxIterations,
+		MaxPixels:     DefaultMaxPixels,
+	})
 }
 
-func getColors(img image.Image) []color.Color {
-	bounds := img.Bounds()
-	pixelCount := (bounds.Max.X - bounds.Min.X) * (bounds.Max.Y - bounds.Min.Y)
-	colors := make([]color.Color, pixelCount)
-	i := 0
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			colors[i] = img.At(x, y)
-			i++
-		}
+// ExtractWithOptions finds the opts.K most dominant colors in the given image,
+// as configured by opts.
+func ExtractWithOptions(img image.Image, opts Options) (*Palette, error) {
+	return ExtractContext(context.Background(), img, opts)
+}
+
+// ExtractContext is like ExtractWithOptions, but stops early and returns
+// ctx.Err() if ctx is done before extraction finishes. Cancellation is checked
+// between k-means iterations.
+//
+// ExtractContext is shorthand for the Extract method of KMeans.
+func ExtractContext(ctx context.Context, img image.Image, opts Options) (*Palette, error) {
+	return KMeans{opts}.Extract(ctx, img)
+}
+
+// An algorithm extracts a palette from the sampled colors of an image.
+type algorithm func(ctx context.Context, colors []color.RGBA64, opts Options) (*Palette, error)
+
+// extract validates opts, samples the pixels of the given image, and extracts
+// a palette from them using the given algorithm, which stops early if ctx is
+// done or opts.Timeout elapses.
+func extract(ctx context.Context, img image.Image, opts Options, fn algorithm) (*Palette, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
-	return colors
+	return fn(ctx, samplePixels(img, opts), opts)
 }
@@ -2,6 +2,7 @@ package palettor
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"image/png"
@@ -18,13 +19,9 @@ func Example() {
 		log.Fatal(err)
 	}
 
-	// For a real-world use case, it's best to use something like
-	// github.com/nfnt/resize to transform images into a manageable size before
-	// extracting colors:
-	//
-	//     img = resize.Thumbnail(200, 200, img, resize.Lanczos3)
-	//
-	// In this example, we're already starting from a tiny image.
+	// Extract samples at most DefaultMaxPixels pixels from larger images, so
+	// real-world images can be passed in directly. In this example, we're
+	// starting from a tiny image anyway.
 
 	// Extract the 3 most dominant colors, halting the clustering algorithm
 	// after 100 iterations if the clusters have not yet converged.
@@ -38,7 +35,34 @@ func Example() {
 	}
 
 	// Example output:
-	// color: {255 0 0 255}; weight: 0.25
-	// color: {255 255 255 255}; weight: 0.25
-	// color: {0 0 0 255}; weight: 0.5
+	// color: {65535 0 0 65535}; weight: 0.25
+	// color: {65535 65535 65535 65535}; weight: 0.25
+	// color: {0 0 0 65535}; weight: 0.5
+}
+
+func ExampleExtractor() {
+	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(testImageData))
+	img, err := png.Decode(decoder)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The extraction algorithm can be chosen at runtime, e.g. from
+	// configuration, since every algorithm implements Extractor.
+	opts := Options{K: 2}
+	extractors := map[string]Extractor{
+		"median cut": MedianCut{opts},
+		"octree":     Octree{opts},
+	}
+	for _, name := range []string{"median cut", "octree"} {
+		palette, err := extractors[name].Extract(context.Background(), img)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s: %d colors\n", name, palette.Count())
+	}
+
+	// Output:
+	// median cut: 2 colors
+	// octree: 2 colors
 }
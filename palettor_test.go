@@ -2,9 +2,11 @@ package palettor
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"image/png"
 	"testing"
+	"time"
 )
 
 // base64-encoded 4x4 png, w/ black, white, red, & blue pixels
@@ -27,3 +29,72 @@ func TestExtract(t *testing.T) {
 		t.Errorf("expected 4 colors, got %d", palette.Count())
 	}
 }
+
+func TestExtractWithOptions(t *testing.T) {
+	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(testImageData))
+	img, err := png.Decode(decoder)
+	if err != nil {
+		t.Fatalf("invalid test image: %s", err)
+	}
+
+	if _, err := ExtractWithOptions(img, Options{}); err == nil {
+		t.Errorf("k is required, expected an error")
+	}
+
+	if _, err := ExtractWithOptions(img, Options{K: 2, MaxIterations: -1}); err == nil {
+		t.Errorf("negative max iterations, expected an error")
+	}
+
+	if _, err := ExtractWithOptions(img, Options{K: 2, Tolerance: -1}); err == nil {
+		t.Errorf("negative tolerance, expected an error")
+	}
+
+	if _, err := ExtractWithOptions(img, Options{K: 2, Restarts: -1}); err == nil {
+		t.Errorf("negative restarts, expected an error")
+	}
+
+	palette, err := ExtractWithOptions(img, Options{K: 2, Seed: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != 2 {
+		t.Errorf("expected 2 colors, got %d", palette.Count())
+	}
+	if palette.Iterations() > DefaultMaxIterations {
+		t.Errorf("expected at most %d iterations, got %d", DefaultMaxIterations, palette.Iterations())
+	}
+}
+
+func TestExtractContext(t *testing.T) {
+	img := loadTestImage(t, "testdata/resized.jpg")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExtractContext(ctx, img, Options{K: 3}); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	if _, err := ExtractContext(context.Background(), img, Options{K: 3, Timeout: time.Nanosecond}); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	palette, err := ExtractContext(context.Background(), img, Options{K: 3, Seed: 1, Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Count() != 3 {
+		t.Errorf("expected 3 colors, got %d", palette.Count())
+	}
+}
+
+func BenchmarkExtract1080x1080(b *testing.B) {
+	img := loadTestImage(b, "testdata/original.jpg")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ExtractWithOptions(img, Options{K: 4, MaxIterations: 10, Seed: 1}); err != nil {
+			b.Error(err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package palettor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// chunkSize is the number of points processed as a unit by a single worker.
+// Work is always split into chunks of this size, regardless of the number of
+// workers, and per-chunk results are combined in chunk order, so that the
+// results of clustering do not depend on the number of workers.
+const chunkSize = 4096
+
+// chunkCount returns the number of chunks needed to cover n points.
+func chunkCount(n int) int {
+	return (n + chunkSize - 1) / chunkSize
+}
+
+// forEachChunk calls fn with the bounds of each chunk of n points, using up
+// to the given number of concurrent workers. It returns once every chunk has
+// been processed.
+func forEachChunk(n, workers int, fn func(chunk, start, end int)) {
+	chunks := chunkCount(n)
+	bounds := func(chunk int) (int, int) {
+		start := chunk * chunkSize
+		end := start + chunkSize
+		if end > n {
+			end = n
+		}
+		return start, end
+	}
+
+	if workers > chunks {
+		workers = chunks
+	}
+	if workers <= 1 {
+		for chunk := 0; chunk < chunks; chunk++ {
+			start, end := bounds(chunk)
+			fn(chunk, start, end)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	next := int64(-1)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				chunk := int(atomic.AddInt64(&next, 1))
+				if chunk >= chunks {
+					return
+				}
+				start, end := bounds(chunk)
+				fn(chunk, start, end)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+package palettor
+
+import (
+	"image"
+	"image/color"
+)
+
+// pixels is a compact representation of the pixels being clustered, which
+// avoids boxing every pixel in a color.Color interface. Pixels are addressed
+// by index.
+type pixels struct {
+	// colors holds each pixel's alpha-premultiplied color.
+	colors []color.RGBA64
+
+	// coords holds each pixel's three coordinates in the color space used
+	// for clustering, one pixel after another.
+	coords []float32
+
+	// weights holds each pixel's weight, or is nil if every pixel has
+	// weight 1.
+	weights []float32
+
+	// counts holds the number of image pixels that each pixel stands for,
+	// such as the pixels in a histogram bucket, or is nil if every pixel is
+	// a single image pixel.
+	counts []int32
+}
+
+// newPixels converts the given colors, with the given weights and counts,
+// into the given color space.
+func newPixels(colors []color.RGBA64, weights []float32, counts []int32, space ColorSpace) *pixels {
+	coords := make([]float32, 3*len(colors))
+	for i, c := range colors {
+		p := space.rgbPoint(uint32(c.R), uint32(c.G), uint32(c.B))
+		coords[3*i] = float32(p[0])
+		coords[3*i+1] = float32(p[1])
+		coords[3*i+2] = float32(p[2])
+	}
+	return &pixels{
+		colors:  colors,
+		coords:  coords,
+		weights: weights,
+		counts:  counts,
+	}
+}
+
+// count returns the number of pixels.
+func (px *pixels) count() int {
+	return len(px.colors)
+}
+
+// point returns the coordinates of the pixel at index i.
+func (px *pixels) point(i int) point {
+	return point{
+		float64(px.coords[3*i]),
+		float64(px.coords[3*i+1]),
+		float64(px.coords[3*i+2]),
+	}
+}
+
+// weight returns the weight of the pixel at index i.
+func (px *pixels) weight(i int) float64 {
+	if px.weights == nil {
+		return 1
+	}
+	return float64(px.weights[i])
+}
+
+// pixelCount returns the number of image pixels that the pixel at index i
+// stands for.
+func (px *pixels) pixelCount(i int) int {
+	if px.counts == nil {
+		return 1
+	}
+	return int(px.counts[i])
+}
+
+// totalWeight returns the sum of the weights of every pixel.
+func (px *pixels) totalWeight() float64 {
+	if px.weights == nil {
+		return float64(px.count())
+	}
+	var total float64
+	for _, w := range px.weights {
+		total += float64(w)
+	}
+	return total
+}
+
+// subset returns the pixels with the given indexes, in the given order.
+func (px *pixels) subset(indexes []int) *pixels {
+	sub := &pixels{
+		colors: make([]color.RGBA64, len(indexes)),
+		coords: make([]float32, 3*len(indexes)),
+	}
+	if px.weights != nil {
+		sub.weights = make([]float32, len(indexes))
+	}
+	if px.counts != nil {
+		sub.counts = make([]int32, len(indexes))
+	}
+	for j, i := range indexes {
+		sub.colors[j] = px.colors[i]
+		copy(sub.coords[3*j:3*j+3], px.coords[3*i:3*i+3])
+		if px.weights != nil {
+			sub.weights[j] = px.weights[i]
+		}
+		if px.counts != nil {
+			sub.counts[j] = px.counts[i]
+		}
+	}
+	return sub
+}
+
+// getPixels reads the colors of every pixel in an image, in row-major order.
+func getPixels(img image.Image) []color.RGBA64 {
+	bounds := img.Bounds()
+	colors := make([]color.RGBA64, 0, bounds.Dx()*bounds.Dy())
+	at := pixelReader(img)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			colors = append(colors, at(x, y))
+		}
+	}
+	return colors
+}
+
+// pixelReader returns a function which reads the color of a pixel in an
+// image. Common image types are read directly, without going through the
+// color.Color interface.
+func pixelReader(img image.Image) func(x, y int) color.RGBA64 {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return func(x, y int) color.RGBA64 { return rgba64(img.RGBAAt(x, y).RGBA()) }
+	case *image.NRGBA:
+		return func(x, y int) color.RGBA64 { return rgba64(img.NRGBAAt(x, y).RGBA()) }
+	case *image.YCbCr:
+		return func(x, y int) color.RGBA64 { return rgba64(img.YCbCrAt(x, y).RGBA()) }
+	case *image.Gray:
+		return func(x, y int) color.RGBA64 { return rgba64(img.GrayAt(x, y).RGBA()) }
+	default:
+		return func(x, y int) color.RGBA64 { return rgba64(img.At(x, y).RGBA()) }
+	}
+}
+
+// toRGBA64 converts a color to a color.RGBA64 value.
+func toRGBA64(c color.Color) color.RGBA64 {
+	return rgba64(c.RGBA())
+}
+
+// rgba64 packs the 16-bit channels returned by color.Color's RGBA method
+// into a color.RGBA64 value.
+func rgba64(r, g, b, a uint32) color.RGBA64 {
+	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+}
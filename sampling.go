@@ -0,0 +1,101 @@
+package palettor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// DefaultMaxPixels is the number of pixels sampled by Extract, which keeps
+// the cost of extraction bounded for images of any size.
+const DefaultMaxPixels = 200 * 200
+
+// A SamplingMethod selects how pixels are sampled from images with more than
+// Options.MaxPixels pixels.
+type SamplingMethod int
+
+// Supported sampling methods
+const (
+	// SampleGrid samples pixels on a regular grid, using the smallest
+	// stride that keeps the number of pixels within the limit.
+	SampleGrid SamplingMethod = iota
+
+	// SampleRandom samples pixels uniformly at random, using the random
+	// number generator seeded by Options.Seed.
+	SampleRandom
+)
+
+// samplePixels reads the colors of the pixels in an image which are selected
+// by the sampling options, in row-major order.
+func samplePixels(img image.Image, opts Options) []color.RGBA64 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	pixelCount := width * height
+
+	stride := opts.Stride
+	if stride < 1 {
+		stride = 1
+	}
+	limited := opts.MaxPixels > 0 && gridCount(width, height, stride) > opts.MaxPixels
+
+	if limited && opts.Sampling == SampleRandom {
+		return sampleRandom(img, opts.MaxPixels, rand.New(rand.NewSource(opts.seed())))
+	}
+	if limited {
+		// Start from the stride that would sample exactly MaxPixels pixels
+		// from a square image, and widen it until the grid fits.
+		if s := int(math.Sqrt(float64(pixelCount) / float64(opts.MaxPixels))); s > stride {
+			stride = s
+		}
+		for gridCount(width, height, stride) > opts.MaxPixels {
+			stride++
+		}
+	}
+	if stride == 1 {
+		return getPixels(img)
+	}
+	return sampleGrid(img, stride)
+}
+
+// gridCount returns the number of pixels sampled from an image of the given
+// size with the given stride.
+func gridCount(width, height, stride int) int {
+	return ((width + stride - 1) / stride) * ((height + stride - 1) / stride)
+}
+
+// sampleGrid reads every stride-th pixel in each direction.
+func sampleGrid(img image.Image, stride int) []color.RGBA64 {
+	bounds := img.Bounds()
+	colors := make([]color.RGBA64, 0, gridCount(bounds.Dx(), bounds.Dy(), stride))
+	at := pixelReader(img)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stride {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stride {
+			colors = append(colors, at(x, y))
+		}
+	}
+	return colors
+}
+
+// sampleRandom reads n distinct pixels chosen uniformly at random, using
+// selection sampling[1] so that no more than n pixels are held in memory.
+//
+// [1]: Knuth, The Art of Computer Programming, Vol. 2, Algorithm 3.4.2S
+func sampleRandom(img image.Image, n int, r *rand.Rand) []color.RGBA64 {
+	bounds := img.Bounds()
+	remaining := bounds.Dx() * bounds.Dy()
+	colors := make([]color.RGBA64, 0, n)
+	at := pixelReader(img)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if len(colors) == n {
+				return colors
+			}
+			if r.Intn(remaining) < n-len(colors) {
+				colors = append(colors, at(x, y))
+			}
+			remaining--
+		}
+	}
+	return colors
+}
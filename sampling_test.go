@@ -0,0 +1,86 @@
+package palettor
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// gradientImage returns an image in which every pixel has a distinct color.
+func gradientImage(width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	return img
+}
+
+func TestSamplePixels(t *testing.T) {
+	img := gradientImage(250, 100)
+
+	testCases := []struct {
+		name     string
+		opts     Options
+		expected int
+	}{
+		{"no sampling", Options{}, 250 * 100},
+		{"max pixels above image size", Options{MaxPixels: 250 * 100}, 250 * 100},
+		{"stride", Options{Stride: 2}, 125 * 50},
+		{"uneven stride", Options{Stride: 3}, 84 * 34},
+		{"max pixels", Options{MaxPixels: 1000}, 50 * 20},
+		{"max pixels w/ uneven stride", Options{MaxPixels: 999}, 42 * 17},
+		{"max pixels w/ wider stride", Options{MaxPixels: 1000, Stride: 10}, 25 * 10},
+		{"random", Options{MaxPixels: 1000, Sampling: SampleRandom, Seed: 1}, 1000},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			colors := samplePixels(img, tc.opts)
+			if len(colors) != tc.expected {
+				t.Errorf("expected %d pixels, got %d", tc.expected, len(colors))
+			}
+			if tc.opts.MaxPixels > 0 && len(colors) > tc.opts.MaxPixels {
+				t.Errorf("expected at most %d pixels, got %d", tc.opts.MaxPixels, len(colors))
+			}
+		})
+	}
+}
+
+func TestSampleGrid(t *testing.T) {
+	img := gradientImage(4, 4)
+	expected := rgba64s(
+		color.NRGBA{0, 0, 0, 255},
+		color.NRGBA{2, 0, 2, 255},
+		color.NRGBA{0, 2, 2, 255},
+		color.NRGBA{2, 2, 0, 255},
+	)
+	if colors := sampleGrid(img, 2); !reflect.DeepEqual(colors, expected) {
+		t.Errorf("expected %v, got %v", expected, colors)
+	}
+}
+
+func TestSampleRandom(t *testing.T) {
+	img := gradientImage(100, 100)
+	opts := Options{MaxPixels: 500, Sampling: SampleRandom, Seed: 42}
+
+	first := samplePixels(img, opts)
+	if second := samplePixels(img, opts); !reflect.DeepEqual(first, second) {
+		t.Errorf("expected identical samples for identical seeds")
+	}
+
+	seen := make(map[color.RGBA64]bool, len(first))
+	for _, c := range first {
+		if seen[c] {
+			t.Fatalf("expected distinct pixels, got %v twice", c)
+		}
+		seen[c] = true
+	}
+
+	// Every pixel is sampled if the image is small enough
+	if colors := sampleRandom(img, 100*100, rand.New(rand.NewSource(1))); !reflect.DeepEqual(colors, getPixels(img)) {
+		t.Errorf("expected every pixel to be sampled")
+	}
+}
@@ -0,0 +1,68 @@
+package palettor
+
+import "math"
+
+// ClusterStats describes how tightly the pixels of one color in a Palette are
+// clustered around it. Distances are measured by the metric given in Options.
+type ClusterStats struct {
+	// Count is the number of pixels in the cluster, including every pixel in
+	// the histogram buckets clustered when Options.HistogramBits is set.
+	Count int `json:"count"`
+
+	// Variance is the weighted mean of the squared distances between the
+	// cluster's pixels and its color.
+	Variance float64 `json:"variance"`
+
+	// MaxDistance is the distance between the cluster's color and the
+	// furthest of its pixels.
+	MaxDistance float64 `json:"max_distance"`
+}
+
+// clusterStats computes the statistics of each of the given clusters, where
+// labels gives the index of each pixel's cluster and centroids gives the
+// coordinates of each cluster's color. It also returns the inertia of the
+// clusters, i.e. the weighted sum of the squared distances between every
+// pixel and the color of its cluster.
+//
+// Pixels are processed in chunks spread across the given number of workers.
+func clusterStats(px *pixels, labels []int32, centroids []point, metric Metric, workers int) ([]ClusterStats, float64) {
+	type partial struct {
+		stats   []ClusterStats
+		weights []float64
+	}
+	partials := make([]partial, chunkCount(px.count()))
+	forEachChunk(px.count(), workers, func(chunk, start, end int) {
+		p := partial{make([]ClusterStats, len(centroids)), make([]float64, len(centroids))}
+		for x := start; x < end; x++ {
+			i, w := labels[x], px.weight(x)
+			d := metric.Distance(centroids[i], px.point(x))
+			s := &p.stats[i]
+			s.Count += px.pixelCount(x)
+			s.Variance += w * d * d
+			s.MaxDistance = math.Max(s.MaxDistance, d)
+			p.weights[i] += w
+		}
+		partials[chunk] = p
+	})
+
+	stats := make([]ClusterStats, len(centroids))
+	weights := make([]float64, len(centroids))
+	for _, p := range partials {
+		for i, s := range p.stats {
+			stats[i].Count += s.Count
+			stats[i].Variance += s.Variance
+			stats[i].MaxDistance = math.Max(stats[i].MaxDistance, s.MaxDistance)
+			weights[i] += p.weights[i]
+		}
+	}
+
+	// Until now, Variance has held the weighted sum of squared distances
+	var inertia float64
+	for i := range stats {
+		inertia += stats[i].Variance
+		if weights[i] > 0 {
+			stats[i].Variance /= weights[i]
+		}
+	}
+	return stats, inertia
+}
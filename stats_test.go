@@ -0,0 +1,119 @@
+package palettor
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestClusterStats(t *testing.T) {
+	px := newPixels(rgba64s(black, darkGray, red, mostlyRed, blue), []float32{1, 3, 1, 1, 2}, nil, RGB)
+	labels := []int32{0, 0, 1, 1, 1}
+	centroids := []point{px.point(0), px.point(2), {}}
+
+	stats, inertia := clusterStats(px, labels, centroids, EuclideanRGB, 1)
+	if len(stats) != 3 {
+		t.Fatalf("expected stats for 3 clusters, got %d", len(stats))
+	}
+
+	grayDist := EuclideanRGB.Distance(px.point(0), px.point(1))
+	redDist := EuclideanRGB.Distance(px.point(2), px.point(3))
+	blueDist := EuclideanRGB.Distance(px.point(2), px.point(4))
+	expected := []ClusterStats{
+		{Count: 2, Variance: 3 * grayDist * grayDist / 4, MaxDistance: grayDist},
+		{Count: 3, Variance: (redDist*redDist + 2*blueDist*blueDist) / 4, MaxDistance: blueDist},
+		{},
+	}
+	for i := range expected {
+		if stats[i].Count != expected[i].Count {
+			t.Errorf("cluster %d: expected count %d, got %d", i, expected[i].Count, stats[i].Count)
+		}
+		if !approxEqual(stats[i].Variance, expected[i].Variance) {
+			t.Errorf("cluster %d: expected variance %v, got %v", i, expected[i].Variance, stats[i].Variance)
+		}
+		if stats[i].MaxDistance != expected[i].MaxDistance {
+			t.Errorf("cluster %d: expected max distance %v, got %v", i, expected[i].MaxDistance, stats[i].MaxDistance)
+		}
+	}
+	expectedInertia := 3*grayDist*grayDist + redDist*redDist + 2*blueDist*blueDist
+	if !approxEqual(inertia, expectedInertia) {
+		t.Errorf("expected inertia %v, got %v", expectedInertia, inertia)
+	}
+}
+
+func TestPaletteStats(t *testing.T) {
+	img := loadTestImage(t, "testdata/resized.jpg")
+	pixelCount := img.Bounds().Dx() * img.Bounds().Dy()
+
+	extractors := map[string]Extractor{
+		"kmeans":     KMeans{Options{K: 4, Seed: 1}},
+		"median cut": MedianCut{Options{K: 4}},
+		"octree":     Octree{Options{K: 4}},
+
+		// Counts are pixels, not histogram buckets
+		"kmeans histogram":     KMeans{Options{K: 4, Seed: 1, HistogramBits: DefaultHistogramBits}},
+		"median cut histogram": MedianCut{Options{K: 4, HistogramBits: DefaultHistogramBits}},
+		"octree histogram":     Octree{Options{K: 4, HistogramBits: DefaultHistogramBits}},
+	}
+	for name, extractor := range extractors {
+		palette, err := extractor.Extract(context.Background(), img)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		// The inertia is the sum of the clusters' weighted variances
+		var count int
+		var inertia float64
+		for _, entry := range palette.Entries() {
+			stats := palette.Stats(entry.Color)
+			if stats.Count == 0 {
+				t.Errorf("%s: expected pixels in cluster of %v", name, entry.Color)
+			}
+			if stats.MaxDistance < math.Sqrt(stats.Variance) {
+				t.Errorf("%s: expected max distance %v to be at least the standard deviation %v", name, stats.MaxDistance, math.Sqrt(stats.Variance))
+			}
+			count += stats.Count
+			inertia += stats.Variance * entry.Weight * float64(pixelCount)
+		}
+		if count != pixelCount {
+			t.Errorf("%s: expected %d pixels across clusters, got %d", name, pixelCount, count)
+		}
+		if palette.Inertia() <= 0 || !approxEqual(palette.Inertia(), inertia) {
+			t.Errorf("%s: expected inertia %v, got %v", name, inertia, palette.Inertia())
+		}
+	}
+
+	palette, err := KMeans{Options{K: 2, Seed: 1, Init: InitKMeansPlusPlus}}.Extract(context.Background(), blocksImage([]color.Color{color.Black, color.White}, []int{1, 1}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if palette.Inertia() != 0 {
+		t.Errorf("expected zero inertia when every color has its own cluster, got %v", palette.Inertia())
+	}
+	if stats := palette.Stats(color.Black); stats != (ClusterStats{Count: 10}) {
+		t.Errorf("expected a single tight cluster of black, got %+v", stats)
+	}
+	if stats := palette.Stats(color.RGBA{255, 0, 0, 255}); stats != (ClusterStats{}) {
+		t.Errorf("expected zero stats for an unknown color, got %+v", stats)
+	}
+
+	// Each flat color fills a single histogram bucket, which still counts
+	// every one of its pixels
+	colors := []color.Color{color.RGBA{200, 30, 30, 255}, color.RGBA{30, 200, 30, 255}, color.RGBA{30, 30, 200, 255}}
+	palette, err = KMeans{Options{K: 3, Seed: 1, Init: InitKMeansPlusPlus, HistogramBits: 5}}.Extract(context.Background(), blocksImage(colors, []int{15, 3, 2}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, expected := range []int{150, 30, 20} {
+		if stats := palette.Stats(colors[i]); stats.Count != expected {
+			t.Errorf("expected %d pixels in cluster of %v, got %+v", expected, colors[i], stats)
+		}
+	}
+}
+
+// approxEqual reports whether two floats are equal to within a relative
+// tolerance of 1e-9.
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}